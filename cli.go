@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+	"unicode/utf8"
+
+	"meili_dog/config"
+	"meili_dog/handlers"
+	"meili_dog/middleware"
+	"meili_dog/models"
+)
+
+// runCommand 执行命令行子命令，返回进程退出码
+func runCommand(cfg *models.AppConfig, args []string) int {
+	switch args[0] {
+	case "import":
+		return runImport(cfg, args[1:])
+	case "settings":
+		return runSettings(cfg, args[1:])
+	case "help", "-h", "--help":
+		printUsage()
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "未知命令: %s\n\n", args[0])
+		printUsage()
+		return 2
+	}
+}
+
+// printUsage 打印命令行帮助
+func printUsage() {
+	fmt.Fprintln(os.Stderr, `用法:
+  meili_dog                 启动 HTTP 服务
+  meili_dog import [参数]   从 NDJSON/CSV/JSON 文件批量导入文档
+  meili_dog settings apply  将配置文件中声明的索引设置同步到 Meilisearch
+  meili_dog config check    检查配置文件和 MEILI_DOG_* 环境变量
+
+使用 meili_dog <命令> -h 查看命令参数`)
+}
+
+// runImport 批量导入文档
+func runImport(cfg *models.AppConfig, args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	file := fs.String("file", "", "导入文件路径，- 表示标准输入")
+	format := fs.String("format", "", "文件格式: ndjson、csv、json，默认按扩展名推断")
+	index := fs.String("index", "", "目标索引，默认使用配置中的默认索引")
+	batchSize := fs.Int("batch-size", 0, "每批写入的文档数，默认使用 import.batch_size")
+	primaryKey := fs.String("primary-key", "", "文档主键")
+	delimiter := fs.String("csv-delimiter", "", "CSV 分隔符，默认逗号")
+	wait := fs.Bool("wait", false, "等待所有批次任务完成")
+	timeout := fs.Duration("timeout", 5*time.Minute, "配合 -wait 使用的等待时间")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *file == "" {
+		fmt.Fprintln(os.Stderr, "缺少 -file 参数")
+		fs.Usage()
+		return 2
+	}
+
+	opts := handlers.ImportOptions{
+		Format:     *format,
+		BatchSize:  *batchSize,
+		PrimaryKey: *primaryKey,
+	}
+	if opts.Format == "" {
+		opts.Format = handlers.DetectImportFormat("", *file)
+	}
+	if *delimiter != "" {
+		if utf8.RuneCountInString(*delimiter) != 1 {
+			fmt.Fprintln(os.Stderr, "-csv-delimiter 必须是单个字符")
+			return 2
+		}
+		opts.CSVDelimiter, _ = utf8.DecodeRuneInString(*delimiter)
+	}
+
+	input := os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "打开文件失败: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		input = f
+	}
+
+	searchHandler := handlers.NewSearchHandler(*cfg)
+	indexUID := *index
+	if indexUID == "" {
+		indexUID = searchHandler.DefaultIndexUID()
+	}
+
+	result, err := searchHandler.ImportDocuments(context.Background(), indexUID, input, opts)
+	if err == nil && *wait {
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		err = searchHandler.WaitImportBatches(ctx, result)
+	}
+	if result != nil {
+		for _, batch := range result.Batches {
+			if batch.Error != "" {
+				fmt.Printf("批次 %d: %d 个文档, 失败: %s\n", batch.Batch, batch.Documents, batch.Error)
+				continue
+			}
+			if batch.Status != "" {
+				fmt.Printf("批次 %d: %d 个文档, 任务 UID %d, 状态 %s\n", batch.Batch, batch.Documents, batch.TaskUID, batch.Status)
+				continue
+			}
+			fmt.Printf("批次 %d: %d 个文档, 任务 UID %d\n", batch.Batch, batch.Documents, batch.TaskUID)
+		}
+		fmt.Printf("索引 %s: 共 %d 个文档, %d 个批次, 失败 %d 个批次\n",
+			result.IndexUID, result.TotalDocuments, len(result.Batches), result.FailedBatches)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "导入失败: %v\n", err)
+		return 1
+	}
+	if result.FailedBatches > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runSettings 设置相关子命令
+func runSettings(cfg *models.AppConfig, args []string) int {
+	if len(args) == 0 || args[0] != "apply" {
+		fmt.Fprintln(os.Stderr, "用法: meili_dog settings apply [-dry-run] [-wait]")
+		return 2
+	}
+
+	fs := flag.NewFlagSet("settings apply", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "只打印差异，不写入 Meilisearch")
+	wait := fs.Bool("wait", false, "等待设置任务完成")
+	timeout := fs.Duration("timeout", time.Minute, "配合 -wait 使用的等待时间")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	searchHandler := handlers.NewSearchHandler(*cfg)
+	results, err := searchHandler.SyncSettings(context.Background(), *dryRun, false)
+	if len(results) == 0 && err == nil {
+		fmt.Println("配置中没有声明索引设置 ([search.settings] 或 [indexes.settings])")
+		return 0
+	}
+
+	for _, result := range results {
+		fmt.Printf("索引 %s:\n", result.IndexUID)
+		if result.Error != "" {
+			fmt.Printf("  错误: %s\n", result.Error)
+			continue
+		}
+		if len(result.Changes) == 0 {
+			fmt.Println("  设置无变化")
+			continue
+		}
+		for _, change := range result.Changes {
+			current, _ := json.Marshal(change.Current)
+			desired, _ := json.Marshal(change.Desired)
+			fmt.Printf("  ~ %s\n    - %s\n    + %s\n", change.Setting, current, desired)
+		}
+		if *dryRun {
+			continue
+		}
+		fmt.Printf("  已提交, 任务 UID %d\n", result.TaskUID)
+		if *wait {
+			ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+			state, waitErr := searchHandler.WaitTask(ctx, result.TaskUID)
+			cancel()
+			if waitErr != nil {
+				fmt.Printf("  等待任务失败: %v\n", waitErr)
+				err = waitErr
+				continue
+			}
+			fmt.Printf("  任务状态: %s\n", state.Status)
+		}
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "同步失败: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runConfig 配置相关命令
+func runConfig(args []string) int {
+	if len(args) == 0 || args[0] != "check" {
+		fmt.Fprintln(os.Stderr, "用法: meili_dog config check [-config 路径]")
+		return 2
+	}
+
+	fs := flag.NewFlagSet("config check", flag.ContinueOnError)
+	path := fs.String("config", configPath(), "配置文件路径，默认使用 CONFIG_PATH 或 config/config.toml")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	cfg, err := config.LoadConfig(*path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", *path, err)
+		return 1
+	}
+
+	// 认证、跨域、限流和多租户配置在创建组件时校验
+	var problems []string
+	if _, err := middleware.NewAuth(cfg.Auth); err != nil {
+		problems = append(problems, "auth: "+err.Error())
+	}
+	if _, err := middleware.NewCORS(cfg.CORS); err != nil {
+		problems = append(problems, "cors: "+err.Error())
+	}
+	if _, err := middleware.NewRateLimiter(cfg.RateLimit); err != nil {
+		problems = append(problems, "rate_limit: "+err.Error())
+	}
+	if err := handlers.ValidateTenantConfig(*cfg); err != nil {
+		problems = append(problems, "tenant: "+err.Error())
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "%s: 配置有 %d 个问题:\n", *path, len(problems))
+		for _, problem := range problems {
+			fmt.Fprintln(os.Stderr, "  - "+problem)
+		}
+		return 1
+	}
+
+	fmt.Printf("%s: 配置有效\n", *path)
+	return 0
+}
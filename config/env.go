@@ -0,0 +1,226 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix 环境变量覆盖的前缀
+const EnvPrefix = "MEILI_DOG_"
+
+// envName 根据配置项路径生成环境变量名，例如 server.local_port 对应 MEILI_DOG_SERVER_LOCAL_PORT
+func envName(key string) string {
+	return EnvPrefix + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, key)
+}
+
+// envOverrides 应用环境变量覆盖的过程
+type envOverrides struct {
+	values   map[string]string // MEILI_DOG_* 环境变量
+	known    map[string]bool   // 对应某个配置项的环境变量
+	problems []Problem
+}
+
+// applyEnv 用 MEILI_DOG_* 环境变量覆盖配置项
+// 数组用逗号分隔，键值表用 key=value,key=value；数组表和键值表中的表只能覆盖配置文件中已有的元素，
+// 例如 MEILI_DOG_INDEXES_0_UID 和 MEILI_DOG_RATE_LIMIT_GROUPS_SEARCH_RATE
+func applyEnv(config interface{}, environ []string) []Problem {
+	o := &envOverrides{values: make(map[string]string), known: make(map[string]bool)}
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if ok && strings.HasPrefix(name, EnvPrefix) {
+			o.values[name] = value
+		}
+	}
+	if len(o.values) == 0 {
+		return nil
+	}
+
+	o.walk(reflect.ValueOf(config).Elem(), "")
+	for name := range o.values {
+		if !o.known[name] {
+			o.problems = append(o.problems, Problem{Key: name, Message: "未知的环境变量，没有对应的配置项"})
+		}
+	}
+	return o.problems
+}
+
+// walk 遍历配置结构，返回是否有配置项被覆盖
+func (o *envOverrides) walk(v reflect.Value, key string) bool {
+	switch v.Kind() {
+	case reflect.Struct:
+		changed := false
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("toml"), ",")
+			if !field.IsExported() || name == "" || name == "-" {
+				continue
+			}
+			if o.walk(v.Field(i), joinKey(key, name)) {
+				changed = true
+			}
+		}
+		return changed
+	case reflect.Pointer:
+		if v.Type().Elem().Kind() != reflect.Struct {
+			return o.setScalar(v, key)
+		}
+		// 配置文件中没有的表，只有设置了对应的环境变量时才创建
+		target := v
+		if v.IsNil() {
+			target = reflect.New(v.Type().Elem())
+		}
+		if !o.walk(target.Elem(), key) {
+			return false
+		}
+		v.Set(target)
+		return true
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Struct {
+			changed := false
+			for i := 0; i < v.Len(); i++ {
+				if o.walk(v.Index(i), joinKey(key, strconv.Itoa(i))) {
+					changed = true
+				}
+			}
+			return changed
+		}
+		return o.setScalar(v, key)
+	case reflect.Map:
+		if v.Type().Elem().Kind() == reflect.Struct {
+			changed := false
+			for _, mapKey := range v.MapKeys() {
+				elem := reflect.New(v.Type().Elem()).Elem()
+				elem.Set(v.MapIndex(mapKey))
+				if o.walk(elem, joinKey(key, mapKey.String())) {
+					v.SetMapIndex(mapKey, elem)
+					changed = true
+				}
+			}
+			return changed
+		}
+		return o.setScalar(v, key)
+	default:
+		return o.setScalar(v, key)
+	}
+}
+
+// setScalar 用环境变量设置单个配置项
+func (o *envOverrides) setScalar(v reflect.Value, key string) bool {
+	name := envName(key)
+	o.known[name] = true
+	raw, ok := o.values[name]
+	if !ok {
+		return false
+	}
+	if err := setValue(v, raw); err != nil {
+		o.problems = append(o.problems, Problem{Key: name, Message: err.Error()})
+		return false
+	}
+	return true
+}
+
+func joinKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setValue 把字符串解析为配置项的类型
+func setValue(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("无效的时间间隔: %q", raw)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("无效的布尔值: %q", raw)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("无效的整数: %q", raw)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("无效的整数: %q", raw)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("无效的数字: %q", raw)
+		}
+		v.SetFloat(f)
+	case reflect.Pointer:
+		elem := reflect.New(v.Type().Elem())
+		if err := setValue(elem.Elem(), raw); err != nil {
+			return err
+		}
+		v.Set(elem)
+	case reflect.Slice:
+		items := splitList(raw)
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setValue(slice.Index(i), item); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.Map:
+		m := reflect.MakeMap(v.Type())
+		for _, item := range splitList(raw) {
+			k, val, ok := strings.Cut(item, "=")
+			if !ok {
+				return fmt.Errorf("键值表格式应为 key=value,key=value: %q", raw)
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := setValue(elem, strings.TrimSpace(val)); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(strings.TrimSpace(k)).Convert(v.Type().Key()), elem)
+		}
+		v.Set(m)
+	default:
+		return fmt.Errorf("不支持通过环境变量设置 %s 类型的配置项", v.Type())
+	}
+	return nil
+}
+
+// splitList 按逗号拆分数组，空字符串表示空数组
+func splitList(raw string) []string {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	items := strings.Split(raw, ",")
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+	}
+	return items
+}
@@ -1,16 +1,67 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
 	"meili_dog/models"
 
 	"github.com/BurntSushi/toml"
 )
 
 // LoadConfig 从TOML文件加载配置
+// 依次应用 MEILI_DOG_* 环境变量覆盖、读取密钥文件并校验配置；未知的配置项和环境变量视为错误
 func LoadConfig(path string) (*models.AppConfig, error) {
 	var config models.AppConfig
-	if _, err := toml.DecodeFile(path, &config); err != nil {
+	meta, err := toml.DecodeFile(path, &config)
+	if err != nil {
 		return nil, err
 	}
+
+	problems := unknownKeys(meta.Undecoded())
+	problems = append(problems, applyEnv(&config, os.Environ())...)
+	problems = append(problems, loadSecrets(&config)...)
+
+	var invalid *ValidationError
+	if err := Validate(&config); errors.As(err, &invalid) {
+		problems = append(problems, invalid.Problems...)
+	}
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
 	return &config, nil
 }
+
+// unknownKeys 报告没有对应字段的配置项，未知的表只报告表本身
+func unknownKeys(keys []toml.Key) []Problem {
+	reported := make(map[string]bool, len(keys))
+	var problems []Problem
+	for _, key := range keys {
+		name := key.String()
+		if len(key) > 1 && reported[key[:len(key)-1].String()] {
+			reported[name] = true
+			continue
+		}
+		reported[name] = true
+		problems = append(problems, Problem{Key: name, Message: "未知的配置项"})
+	}
+	return problems
+}
+
+// loadSecrets 从文件读取密钥，避免把密钥写在配置文件中
+func loadSecrets(config *models.AppConfig) []Problem {
+	if config.Server.APIKeyFile == "" {
+		return nil
+	}
+	if config.Server.APIKey != "" {
+		return []Problem{{Key: "server.api_key_file", Message: "不能与 server.api_key 同时设置"}}
+	}
+	data, err := os.ReadFile(config.Server.APIKeyFile)
+	if err != nil {
+		return []Problem{{Key: "server.api_key_file", Message: fmt.Sprintf("读取密钥文件失败: %v", err)}}
+	}
+	config.Server.APIKey = strings.TrimSpace(string(data))
+	return nil
+}
@@ -0,0 +1,203 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"meili_dog/logging"
+	"meili_dog/models"
+	"meili_dog/schema"
+)
+
+// Problem 单个配置问题
+type Problem struct {
+	Key     string // 配置项路径或环境变量名
+	Message string
+}
+
+func (p Problem) String() string {
+	return p.Key + ": " + p.Message
+}
+
+// ValidationError 配置校验失败，包含所有发现的问题
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Problems)+1)
+	lines = append(lines, fmt.Sprintf("配置有 %d 个问题:", len(e.Problems)))
+	for _, p := range e.Problems {
+		lines = append(lines, "  - "+p.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// indexUIDPattern Meilisearch 索引 UID 只能包含字母、数字、- 和 _
+var indexUIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,400}$`)
+
+// Validate 检查配置的取值，认证、跨域、限流和多租户配置在创建对应组件时校验
+func Validate(config *models.AppConfig) error {
+	v := &validator{}
+	v.server(config)
+	v.indexes(config)
+	v.fields("fields", &config.Fields)
+	v.searchDefault(&config.Search.Default)
+	v.optimization("search.optimization", &config.Search.Optimization)
+
+	v.nonNegative("import.batch_size", config.Import.BatchSize)
+	v.nonNegative("cache.max_entries", config.Cache.MaxEntries)
+	v.nonNegative("cache.ttl", config.Cache.TTL)
+	v.nonNegative("snapshots.max_per_index", config.Snapshots.MaxPerIndex)
+	v.nonNegative("reload.interval", config.Reload.Interval)
+
+	if path := config.Metrics.Path; path != "" && !strings.HasPrefix(path, "/") {
+		v.add("metrics.path", "必须以 / 开头")
+	}
+	if listen := config.Metrics.Listen; listen != "" {
+		if _, _, err := net.SplitHostPort(listen); err != nil {
+			v.add("metrics.listen", fmt.Sprintf("应为 host:port 格式: %q", listen))
+		}
+	}
+
+	if _, err := logging.NewHandler(io.Discard, config.Log); err != nil {
+		v.add("log", err.Error())
+	}
+	switch strings.ToLower(config.Tracing.Exporter) {
+	case "", "otlp", "stdout":
+	default:
+		v.add("tracing.exporter", fmt.Sprintf("未知的导出器 %q，可选 otlp、stdout", config.Tracing.Exporter))
+	}
+	if ratio := config.Tracing.SampleRatio; ratio < 0 || ratio > 1 {
+		v.add("tracing.sample_ratio", "必须在 0 到 1 之间")
+	}
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+type validator struct {
+	problems []Problem
+}
+
+func (v *validator) add(key, message string) {
+	v.problems = append(v.problems, Problem{Key: key, Message: message})
+}
+
+func (v *validator) nonNegative(key string, value int) {
+	if value < 0 {
+		v.add(key, "不能为负数")
+	}
+}
+
+func (v *validator) server(config *models.AppConfig) {
+	address, err := url.Parse(config.Server.Address)
+	switch {
+	case config.Server.Address == "":
+		v.add("server.address", "未设置 Meilisearch 地址")
+	case err != nil:
+		v.add("server.address", fmt.Sprintf("无效的 URL: %v", err))
+	case address.Scheme != "http" && address.Scheme != "https":
+		v.add("server.address", fmt.Sprintf("应为 http:// 或 https:// 开头的地址: %q", config.Server.Address))
+	case address.Host == "":
+		v.add("server.address", fmt.Sprintf("缺少主机名: %q", config.Server.Address))
+	}
+
+	// 设置了 PORT 环境变量时使用 PORT
+	port := config.Server.LocalPort
+	if port < 0 || port > 65535 || (port == 0 && os.Getenv("PORT") == "") {
+		v.add("server.local_port", "必须在 1-65535 之间")
+	}
+
+	for i, proxy := range config.Server.TrustedProxies {
+		if _, err := netip.ParseAddr(proxy); err == nil {
+			continue
+		}
+		if _, err := netip.ParsePrefix(proxy); err != nil {
+			v.add(fmt.Sprintf("server.trusted_proxies[%d]", i), fmt.Sprintf("应为 IP 地址或网段: %q", proxy))
+		}
+	}
+}
+
+func (v *validator) indexes(config *models.AppConfig) {
+	if config.Search.IndexUID == "" && len(config.Indexes) == 0 {
+		v.add("search.index_uid", "未设置索引UID (search.index_uid 或 [[indexes]])")
+	}
+	if uid := config.Search.IndexUID; uid != "" && !indexUIDPattern.MatchString(uid) {
+		v.add("search.index_uid", fmt.Sprintf("无效的索引UID %q，只能包含字母、数字、- 和 _", uid))
+	}
+
+	seen := make(map[string]bool, len(config.Indexes))
+	for i, idx := range config.Indexes {
+		key := fmt.Sprintf("indexes[%d]", i)
+		switch {
+		case idx.UID == "":
+			v.add(key+".uid", "未设置索引UID")
+		case !indexUIDPattern.MatchString(idx.UID):
+			v.add(key+".uid", fmt.Sprintf("无效的索引UID %q，只能包含字母、数字、- 和 _", idx.UID))
+		case seen[idx.UID]:
+			v.add(key+".uid", fmt.Sprintf("索引 %q 重复", idx.UID))
+		}
+		seen[idx.UID] = true
+		if idx.Optimization != nil {
+			v.optimization(key+".optimization", idx.Optimization)
+		}
+		if idx.Fields != nil {
+			v.fields(key+".fields", idx.Fields)
+		}
+	}
+}
+
+// searchDefault 默认参数不能超出限制，未设置的参数和限制按处理请求时的默认值检查
+func (v *validator) searchDefault(d *models.SearchDefault) {
+	v.nonNegative("search.default.limit", d.Limit)
+	v.nonNegative("search.default.offset", d.Offset)
+	v.nonNegative("search.default.max_limit", d.MaxLimit)
+	v.nonNegative("search.default.max_page_depth", d.MaxPageDepth)
+	v.nonNegative("search.default.max_query_length", d.MaxQueryLength)
+
+	limit := positiveOr(d.Limit, models.DefaultSearchLimit)
+	maxLimit := positiveOr(d.MaxLimit, models.DefaultMaxLimit)
+	maxPageDepth := positiveOr(d.MaxPageDepth, models.DefaultMaxPageDepth)
+	if limit > maxLimit {
+		v.add("search.default.limit", fmt.Sprintf("每页数量 %d 不能超过 search.default.max_limit (%d)", limit, maxLimit))
+	}
+	if d.Offset+limit > maxPageDepth {
+		v.add("search.default.offset", fmt.Sprintf("offset + limit (%d) 不能超过 search.default.max_page_depth (%d)", d.Offset+limit, maxPageDepth))
+	}
+}
+
+// positiveOr 未设置（不大于 0）时返回默认值
+func positiveOr(value, fallback int) int {
+	if value > 0 {
+		return value
+	}
+	return fallback
+}
+
+// fields 字段名不能重复，可过滤和可排序字段必须已声明类型
+func (v *validator) fields(key string, cfg *models.FieldConfig) {
+	var invalid *schema.Error
+	if _, err := schema.New(cfg); errors.As(err, &invalid) {
+		for _, p := range invalid.Problems {
+			v.add(key+"."+p.Key, p.Message)
+		}
+	}
+}
+
+// optimization 高亮标签必须成对设置
+func (v *validator) optimization(key string, opt *models.SearchOptimization) {
+	if (opt.HighlightPreTag == "") != (opt.HighlightPostTag == "") {
+		v.add(key, "highlight_pre_tag 和 highlight_post_tag 必须同时设置")
+	}
+	v.nonNegative(key+".crop_length", opt.CropLength)
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"meili_dog/models"
+)
+
+// validConfig 通过校验的最小配置
+func validConfig() *models.AppConfig {
+	cfg := &models.AppConfig{}
+	cfg.Server.Address = "http://127.0.0.1:7700"
+	cfg.Server.LocalPort = 8081
+	cfg.Search.IndexUID = "users"
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(cfg *models.AppConfig)
+		wantKeys []string // 期望出现问题的配置项，为空表示配置有效
+	}{
+		{
+			name:   "最小配置",
+			modify: func(cfg *models.AppConfig) {},
+		},
+		{
+			name:     "缺少 Meilisearch 地址",
+			modify:   func(cfg *models.AppConfig) { cfg.Server.Address = "" },
+			wantKeys: []string{"server.address"},
+		},
+		{
+			name:     "地址缺少协议",
+			modify:   func(cfg *models.AppConfig) { cfg.Server.Address = "127.0.0.1:7700" },
+			wantKeys: []string{"server.address"},
+		},
+		{
+			name:     "端口超出范围",
+			modify:   func(cfg *models.AppConfig) { cfg.Server.LocalPort = 70000 },
+			wantKeys: []string{"server.local_port"},
+		},
+		{
+			name:     "可信代理格式错误",
+			modify:   func(cfg *models.AppConfig) { cfg.Server.TrustedProxies = []string{"10.0.0.0/8", "proxy"} },
+			wantKeys: []string{"server.trusted_proxies[1]"},
+		},
+		{
+			name:     "缺少索引",
+			modify:   func(cfg *models.AppConfig) { cfg.Search.IndexUID = "" },
+			wantKeys: []string{"search.index_uid"},
+		},
+		{
+			name: "索引重复",
+			modify: func(cfg *models.AppConfig) {
+				cfg.Indexes = []models.IndexConfig{{UID: "orders"}, {UID: "orders"}, {UID: "bad uid"}}
+			},
+			wantKeys: []string{"indexes[1].uid", "indexes[2].uid"},
+		},
+		{
+			name:     "默认每页数量超过默认上限",
+			modify:   func(cfg *models.AppConfig) { cfg.Search.Default.Limit = models.DefaultMaxLimit + 1 },
+			wantKeys: []string{"search.default.limit", "search.default.offset"},
+		},
+		{
+			name: "默认每页数量不超过配置的上限",
+			modify: func(cfg *models.AppConfig) {
+				cfg.Search.Default.Limit = 2000
+				cfg.Search.Default.MaxLimit = 5000
+				cfg.Search.Default.MaxPageDepth = 5000
+			},
+		},
+		{
+			name:     "默认 offset 超过默认分页深度",
+			modify:   func(cfg *models.AppConfig) { cfg.Search.Default.Offset = models.DefaultMaxPageDepth },
+			wantKeys: []string{"search.default.offset"},
+		},
+		{
+			name:     "负数",
+			modify:   func(cfg *models.AppConfig) { cfg.Cache.TTL = -1 },
+			wantKeys: []string{"cache.ttl"},
+		},
+		{
+			name: "高亮标签不成对",
+			modify: func(cfg *models.AppConfig) {
+				cfg.Search.Optimization.HighlightPreTag = "<em>"
+			},
+			wantKeys: []string{"search.optimization"},
+		},
+		{
+			name: "字段类型",
+			modify: func(cfg *models.AppConfig) {
+				cfg.Fields.NumberFields = models.FieldGroup{Names: []string{"id", "price"}, Filterable: []string{"id"}, Sortable: []string{"name"}}
+				cfg.Fields.StringFields = models.FieldGroup{Names: []string{"price"}}
+			},
+			wantKeys: []string{"fields.number.names", "fields.number.sortable"},
+		},
+		{
+			name:     "指标路径",
+			modify:   func(cfg *models.AppConfig) { cfg.Metrics.Path = "metrics" },
+			wantKeys: []string{"metrics.path"},
+		},
+		{
+			name:     "链路追踪采样率",
+			modify:   func(cfg *models.AppConfig) { cfg.Tracing.SampleRatio = 2 },
+			wantKeys: []string{"tracing.sample_ratio"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PORT", "")
+			cfg := validConfig()
+			tt.modify(cfg)
+
+			err := Validate(cfg)
+			if len(tt.wantKeys) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() = %v，期望配置有效", err)
+				}
+				return
+			}
+
+			var invalid *ValidationError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("Validate() = %v，期望 *ValidationError", err)
+			}
+			keys := make([]string, 0, len(invalid.Problems))
+			for _, p := range invalid.Problems {
+				keys = append(keys, p.Key)
+			}
+			slices.Sort(keys)
+			want := slices.Clone(tt.wantKeys)
+			slices.Sort(want)
+			if !slices.Equal(keys, want) {
+				t.Errorf("问题 = %v，期望 %v", invalid.Problems, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+
+	"meili_dog/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// redactedValue 隐藏后的密钥
+const redactedValue = "******"
+
+// startupOnlyKeys 只在启动时读取的配置项，修改后需要重启才能生效
+var startupOnlyKeys = []string{
+	"server.local_port",
+	"server.trusted_proxies",
+	"cache",
+	"metrics",
+	"snapshots",
+	"tracing",
+	"auth",
+	"cors",
+	"rate_limit",
+	"reload",
+}
+
+// Reload 替换可热加载的配置，进行中的请求继续使用各自读取到的配置
+// 返回已修改但需要重启才能生效的配置项
+func (h *SearchHandler) Reload(config models.AppConfig) ([]string, error) {
+	// auth 只在启动时读取，多租户配置按正在使用的认证配置校验
+	running := config
+	running.Auth = h.startup.Auth
+	if err := ValidateTenantConfig(running); err != nil {
+		return nil, err
+	}
+
+	h.current.Store(newHandlerState(config))
+	// 索引和优化参数可能已变化，缓存的结果和可过滤、可排序字段不再可靠
+	h.cache.invalidate("")
+	h.invalidateAttributes("")
+	// Meilisearch 地址可能已变化
+	h.primaryKeys.Clear()
+	return h.restartRequired(config), nil
+}
+
+// GetConfig 返回当前生效的配置，密钥已隐藏
+// 需要重启才能生效的配置项显示启动时的值，并列在 restart_required 中
+func (h *SearchHandler) GetConfig(c *gin.Context) {
+	st := h.state()
+
+	effective := st.config
+	for _, key := range startupOnlyKeys {
+		configField(&effective, key).Set(configField(&h.startup, key))
+	}
+
+	c.JSON(http.StatusOK, models.ConfigResponse{
+		Config:          redactConfig(reflect.ValueOf(effective)).(map[string]interface{}),
+		LoadedAt:        st.loadedAt,
+		RestartRequired: h.restartRequired(st.config),
+	})
+}
+
+// restartRequired 与启动时的配置比较，列出修改后需要重启才能生效的配置项
+func (h *SearchHandler) restartRequired(config models.AppConfig) []string {
+	keys := []string{}
+	for _, key := range startupOnlyKeys {
+		if !reflect.DeepEqual(configField(&config, key).Interface(), configField(&h.startup, key).Interface()) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// configField 按 TOML 路径查找配置字段，路径来自 startupOnlyKeys，不存在时 panic
+func configField(config *models.AppConfig, key string) reflect.Value {
+	v := reflect.ValueOf(config).Elem()
+	for _, name := range strings.Split(key, ".") {
+		field, ok := fieldByTOMLName(v.Type(), name)
+		if !ok {
+			panic("未知的配置项: " + key)
+		}
+		v = v.FieldByIndex(field.Index)
+	}
+	return v
+}
+
+func fieldByTOMLName(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if tomlName(field) == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func tomlName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("toml"), ",")
+	return name
+}
+
+// redactConfig 把配置转换为与配置文件相同结构的 JSON 对象，带 secret 标签的字段不为空时替换为 ******
+func redactConfig(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return redactConfig(v.Elem())
+	case reflect.Struct:
+		fields := make(map[string]interface{}, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			name := tomlName(field)
+			if !field.IsExported() || name == "" || name == "-" {
+				continue
+			}
+			value := v.Field(i)
+			if field.Tag.Get("secret") == "true" {
+				fields[name] = redactSecret(value)
+				continue
+			}
+			fields[name] = redactConfig(value)
+		}
+		return fields
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = redactConfig(v.Index(i))
+		}
+		return items
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		entries := make(map[string]interface{}, v.Len())
+		for _, key := range v.MapKeys() {
+			entries[key.String()] = redactConfig(v.MapIndex(key))
+		}
+		return entries
+	default:
+		return v.Interface()
+	}
+}
+
+// redactSecret 隐藏密钥，键值表只隐藏值
+func redactSecret(v reflect.Value) interface{} {
+	if v.Kind() == reflect.Map {
+		entries := make(map[string]interface{}, v.Len())
+		for _, key := range v.MapKeys() {
+			entries[key.String()] = redactedValue
+		}
+		return entries
+	}
+	if v.IsZero() {
+		return ""
+	}
+	return redactedValue
+}
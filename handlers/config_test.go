@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestReload(t *testing.T) {
+	const searchResult = `{"hits":[],"estimatedTotalHits":0,"processingTimeMs":1}`
+	before, after := newFakeMeili(t), newFakeMeili(t)
+	before.reply("POST /indexes/users/search", http.StatusOK, searchResult)
+	after.reply("POST /indexes/products/search", http.StatusOK, searchResult)
+
+	cfg := testConfig(t, before)
+	cfg.Server.APIKey = "old-master-key"
+	cfg.Cache.Enabled = true
+	h := NewSearchHandler(cfg)
+	router := testRouter(t, func(r gin.IRoutes) {
+		r.GET("/search", h.Search)
+		r.GET("/admin/config", h.GetConfig)
+	})
+
+	for _, want := range []string{"MISS", "HIT"} {
+		w := do(router, http.MethodGet, "/search?query=dog", "search-key", "")
+		if w.Code != http.StatusOK || w.Header().Get(searchCacheHeader) != want {
+			t.Fatalf("重新加载前搜索 = %d %s，期望 200 %s：%s", w.Code, w.Header().Get(searchCacheHeader), want, w.Body.String())
+		}
+	}
+
+	next := testConfig(t, after)
+	next.Snapshots = cfg.Snapshots
+	next.Server.APIKey = "new-master-key"
+	next.Search.IndexUID = "products"
+	next.Cache.Enabled = true
+	next.Cache.TTL = 5
+	restartRequired, err := h.Reload(next)
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if !slices.Equal(restartRequired, []string{"cache"}) {
+		t.Errorf("Reload() = %v，期望 [cache]", restartRequired)
+	}
+	if h.cache.lru.Len() != 0 {
+		t.Errorf("重新加载后缓存 %d 条，期望清空", h.cache.lru.Len())
+	}
+
+	// 新的 Meilisearch 地址和默认索引立即生效
+	w := do(router, http.MethodGet, "/search?query=dog", "search-key", "")
+	if w.Code != http.StatusOK || w.Header().Get(searchCacheHeader) != "MISS" {
+		t.Fatalf("重新加载后搜索 = %d %s，期望 200 MISS：%s", w.Code, w.Header().Get(searchCacheHeader), w.Body.String())
+	}
+	if n := len(before.received("POST /indexes/users/search")); n != 1 {
+		t.Errorf("旧地址收到 %d 次搜索，期望 1 次", n)
+	}
+	if n := len(after.received("POST /indexes/products/search")); n != 1 {
+		t.Errorf("新地址收到 %d 次搜索，期望 1 次", n)
+	}
+
+	// 校验失败时保留当前配置
+	invalid := next
+	invalid.Search.IndexUID = "orders"
+	invalid.Tenant.Enabled = true
+	if _, err := h.Reload(invalid); err == nil {
+		t.Fatal("Reload() 成功，期望因为未启用认证而失败")
+	}
+
+	w = do(router, http.MethodGet, "/admin/config", "admin-key", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /admin/config = %d：%s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Config struct {
+			Server struct {
+				APIKey string `json:"api_key"`
+			} `json:"server"`
+			Search struct {
+				IndexUID string `json:"index_uid"`
+			} `json:"search"`
+			Cache struct {
+				TTL int `json:"ttl"`
+			} `json:"cache"`
+		} `json:"config"`
+		RestartRequired []string `json:"restart_required"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Config.Server.APIKey != redactedValue {
+		t.Errorf("server.api_key = %q，期望隐藏", resp.Config.Server.APIKey)
+	}
+	if resp.Config.Search.IndexUID != "products" {
+		t.Errorf("search.index_uid = %q，期望保留上一次成功加载的 products", resp.Config.Search.IndexUID)
+	}
+	if resp.Config.Cache.TTL != 0 {
+		t.Errorf("cache.ttl = %d，需要重启的配置项应显示启动时的值", resp.Config.Cache.TTL)
+	}
+	if !slices.Equal(resp.RestartRequired, []string{"cache"}) {
+		t.Errorf("restart_required = %v，期望 [cache]", resp.RestartRequired)
+	}
+}
+
+// TestReloadInFlight 进行中的请求继续使用读取到的配置
+func TestReloadInFlight(t *testing.T) {
+	meili := newFakeMeili(t)
+	h := NewSearchHandler(testConfig(t, meili))
+	st := h.state()
+
+	next := testConfig(t, meili)
+	next.Search.IndexUID = "products"
+	if _, err := h.Reload(next); err != nil {
+		t.Fatal(err)
+	}
+	if st.config.Search.IndexUID != "users" {
+		t.Errorf("已读取的配置被修改为 %q", st.config.Search.IndexUID)
+	}
+	if got := h.state().config.Search.IndexUID; got != "products" {
+		t.Errorf("新请求读取到 %q，期望 products", got)
+	}
+}
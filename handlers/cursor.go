@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"meili_dog/models"
+	"meili_dog/schema"
+
+	"github.com/meilisearch/meilisearch-go"
+)
+
+// cursorStart 开始游标分页时 cursor 参数的值
+const cursorStart = "*"
+
+// maxExactInteger float64 能精确表示的最大整数，结果中的数字按 float64 解码，更大的整数比较不可靠
+const maxExactInteger = 1 << 53
+
+// errCursorUnavailable 本页最后一条结果缺少可用的排序值，无法生成下一页的游标
+var errCursorUnavailable = errors.New("无法生成下一页的游标")
+
+// errCursorString 排序值是字符串，游标分页只支持数字排序字段
+// Meilisearch 按规范化（转小写）后的取值比较和排序字符串，用原始取值生成的条件可能重复或遗漏结果
+var errCursorString = fmt.Errorf("%w: 游标分页不支持字符串排序字段，主键是字符串的索引请使用 page/offset 翻页或导出接口", errInvalidSearchParams)
+
+// searchCursor 游标中保存的搜索条件和上一页最后一条结果的排序值
+// 每次请求都按当前配置重新校验过滤条件，修改游标只能构造出调用方本来就能发起的搜索
+type searchCursor struct {
+	IndexUID string                 `json:"i"`
+	Query    string                 `json:"q"`
+	Filters  map[string]interface{} `json:"fs,omitempty"`
+	Filter   *models.FilterNode     `json:"f,omitempty"`
+	Sort     []string               `json:"s"` // 最后一项为主键，保证顺序唯一
+	Limit    int                    `json:"l"`
+	After    []interface{}          `json:"a,omitempty"` // 上一页最后一条结果的排序值，都是数字，第一页为空
+}
+
+// cursorSort 解析后的排序规则
+type cursorSort struct {
+	field string
+	desc  bool
+}
+
+// prepareCursor 开始游标分页或从游标恢复搜索条件，恢复时忽略请求中的搜索词、过滤条件、排序和每页数量
+func (h *SearchHandler) prepareCursor(ctx context.Context, st *handlerState, indexUID string, req *models.SearchRequest) (*searchCursor, error) {
+	if req.Cursor != cursorStart {
+		cursor, err := decodeCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if cursor.IndexUID != indexUID {
+			return nil, fmt.Errorf("%w: cursor 不属于索引 %s", errInvalidSearchParams, indexUID)
+		}
+		req.Query, req.Filters, req.FilterExpr, req.Sort, req.Limit = cursor.Query, cursor.Filters, cursor.Filter, cursor.Sort, cursor.Limit
+		return cursor, nil
+	}
+
+	if len(req.Sort) == 0 {
+		return nil, fmt.Errorf("%w: 游标分页需要设置 sort", errInvalidSearchParams)
+	}
+	primaryKey, err := h.primaryKey(ctx, st, indexUID)
+	if err != nil {
+		return nil, err
+	}
+
+	sorts, err := parseCursorSort(req.Sort)
+	if err != nil {
+		return nil, err
+	}
+	sort := append([]string{}, req.Sort...)
+	if !slices.ContainsFunc(sorts, func(s cursorSort) bool { return s.field == primaryKey }) {
+		sort = append(sort, primaryKey+":asc")
+	}
+	req.Sort = sort
+
+	return &searchCursor{
+		IndexUID: indexUID,
+		Query:    req.Query,
+		Filters:  req.Filters,
+		Filter:   req.FilterExpr,
+		Sort:     sort,
+	}, nil
+}
+
+// applyCursor 在搜索参数中加入排在上一页之后的条件，并确保返回排序字段以便生成下一页的游标
+// 排在上一页之后的条件用 > 和 < 过滤，Meilisearch 只能比较数字，因此排序字段（包括主键）必须是可排序、可过滤的数字字段
+func (h *SearchHandler) applyCursor(ctx context.Context, st *handlerState, cur *searchCursor, req *meilisearch.SearchRequest) error {
+	sorts, err := parseCursorSort(cur.Sort)
+	if err != nil {
+		return err
+	}
+
+	sortable, err := h.sortableAttributes(ctx, st, cur.IndexUID)
+	if err != nil {
+		return err
+	}
+	filterable, err := h.filterableAttributes(ctx, st, cur.IndexUID)
+	if err != nil {
+		return err
+	}
+	fieldSchema := st.schemas[cur.IndexUID]
+	for _, s := range sorts {
+		if !hasAttribute(s.field, sortable) {
+			return fmt.Errorf("%w: 游标分页的排序字段（包括自动加入的主键）必须可排序: %s", errInvalidSearchParams, s.field)
+		}
+		if !hasAttribute(s.field, filterable) {
+			return fmt.Errorf("%w: 游标分页的排序字段（包括自动加入的主键）必须可过滤: %s", errInvalidSearchParams, s.field)
+		}
+		// 未声明类型的字段在生成下一页的游标时检查取值
+		if fieldSchema != nil {
+			if fieldType, _ := fieldSchema.FieldType(s.field); fieldType == models.FieldTypeString {
+				return fmt.Errorf("%w: %s", errCursorString, s.field)
+			} else if fieldType != "" && fieldType != models.FieldTypeNumber {
+				return fmt.Errorf("%w: 游标分页的排序字段（包括自动加入的主键）必须是数字: %s 是 %s", errInvalidSearchParams, s.field, fieldType)
+			}
+		}
+		if len(req.AttributesToRetrieve) > 0 && !slices.Contains(req.AttributesToRetrieve, s.field) {
+			req.AttributesToRetrieve = append(req.AttributesToRetrieve, s.field)
+		}
+	}
+
+	if len(cur.After) > 0 {
+		keyset, err := keysetFilter(sorts, cur.After)
+		if err != nil {
+			return err
+		}
+		filter, _ := req.Filter.(string)
+		req.Filter = andFilters(filter, keyset)
+	}
+	return nil
+}
+
+// next 根据本页最后一条结果生成下一页的游标，本页不满时返回空字符串，表示没有下一页
+// 最后一条结果缺少排序值或排序值不能用于游标时返回 errCursorUnavailable，不能当作没有下一页；
+// 排序值是字符串时返回 errCursorString，本页不满时同样返回，避免只在结果较多时才出错
+func (cur *searchCursor) next(hits []map[string]interface{}, limit int) (string, error) {
+	if len(hits) == 0 {
+		return "", nil
+	}
+
+	sorts, err := parseCursorSort(cur.Sort)
+	if err != nil {
+		return "", err
+	}
+	last := hits[len(hits)-1]
+	for _, s := range sorts {
+		if _, ok := documentValue(last, s.field).(string); ok {
+			return "", fmt.Errorf("%w: %s", errCursorString, s.field)
+		}
+	}
+	if len(hits) < limit {
+		return "", nil
+	}
+	after := make([]interface{}, 0, len(sorts))
+	for _, s := range sorts {
+		value, err := cursorValue(last, s.field)
+		if err != nil {
+			return "", err
+		}
+		after = append(after, value)
+	}
+
+	next := *cur
+	next.Limit = limit
+	next.After = after
+	data, err := json.Marshal(next)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(raw string) (*searchCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: cursor 无效", errInvalidSearchParams)
+	}
+
+	// 按 json.Number 解码，便于确认排序值都是数字，并且原样写回过滤条件；生成的游标不包含字符串排序值
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var cursor searchCursor
+	if err := decoder.Decode(&cursor); err != nil || len(cursor.Sort) == 0 || cursor.Limit < 1 {
+		return nil, fmt.Errorf("%w: cursor 无效", errInvalidSearchParams)
+	}
+	if len(cursor.After) != len(cursor.Sort) {
+		return nil, fmt.Errorf("%w: cursor 无效", errInvalidSearchParams)
+	}
+	for _, v := range cursor.After {
+		if _, ok := v.(json.Number); !ok {
+			return nil, fmt.Errorf("%w: cursor 无效", errInvalidSearchParams)
+		}
+	}
+	return &cursor, nil
+}
+
+func parseCursorSort(sort []string) ([]cursorSort, error) {
+	sorts := make([]cursorSort, 0, len(sort))
+	for _, s := range sort {
+		// 游标分页支持的排序规则，不支持 _geoPoint
+		field, order, ok := strings.Cut(s, ":")
+		if !ok || (order != "asc" && order != "desc") || !schema.FieldNamePattern.MatchString(field) {
+			return nil, fmt.Errorf("%w: 游标分页的排序规则应为 field:asc 或 field:desc: %q", errInvalidSearchParams, s)
+		}
+		sorts = append(sorts, cursorSort{field: field, desc: order == "desc"})
+	}
+	return sorts, nil
+}
+
+// keysetFilter 生成排在 after 之后的条件
+// 例如 price:asc, id:asc 生成 (price > 10) OR (price = 10 AND id > 5)
+func keysetFilter(sorts []cursorSort, after []interface{}) (string, error) {
+	values := make([]string, len(after))
+	for i, v := range after {
+		value, err := renderFilterValue(v)
+		if err != nil {
+			return "", fmt.Errorf("%w: cursor 无效", errInvalidSearchParams)
+		}
+		values[i] = value
+	}
+
+	clauses := make([]string, 0, len(sorts))
+	for i, s := range sorts {
+		parts := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			parts = append(parts, sorts[j].field+" = "+values[j])
+		}
+		op := " > "
+		if s.desc {
+			op = " < "
+		}
+		parts = append(parts, s.field+op+values[i])
+		clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+	}
+	return "(" + strings.Join(clauses, " OR ") + ")", nil
+}
+
+// cursorValue 读取结果中的排序值，只支持数字
+// Meilisearch 返回的结果按 float64 解码，超过 2^53 的整数已经丢失精度，不能用于游标
+func cursorValue(hit map[string]interface{}, field string) (json.Number, error) {
+	switch value := documentValue(hit, field).(type) {
+	case float64:
+		if value == math.Trunc(value) && math.Abs(value) > maxExactInteger {
+			return "", fmt.Errorf("%w: 排序字段 %s 的值 %.0f 超出可精确比较的整数范围", errCursorUnavailable, field, value)
+		}
+		return json.Number(strconv.FormatFloat(value, 'f', -1, 64)), nil
+	case nil:
+		return "", fmt.Errorf("%w: 最后一条结果缺少排序字段 %s，该字段可能为空或未包含在 displayedAttributes 中", errCursorUnavailable, field)
+	default:
+		return "", fmt.Errorf("%w: 排序字段 %s 的值不是数字", errCursorUnavailable, field)
+	}
+}
+
+// primaryKey 获取索引主键，主键设置后不会改变，缓存到重新加载配置为止
+func (h *SearchHandler) primaryKey(ctx context.Context, st *handlerState, indexUID string) (string, error) {
+	if key, ok := h.primaryKeys.Load(indexUID); ok {
+		return key.(string), nil
+	}
+
+	call := startUpstream(ctx, "get_index")
+	index, err := st.client.GetIndex(indexUID)
+	call.end(err)
+	if err != nil {
+		return "", fmt.Errorf("获取索引主键失败: %w", err)
+	}
+	if index.PrimaryKey == "" {
+		return "", fmt.Errorf("%w: 索引尚未设置主键，无法使用游标分页", errInvalidSearchParams)
+	}
+	h.primaryKeys.Store(indexUID, index.PrimaryKey)
+	return index.PrimaryKey, nil
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestKeysetFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		sort    []string
+		after   []interface{}
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "单个字段升序",
+			sort:  []string{"id:asc"},
+			after: []interface{}{json.Number("5")},
+			want:  `((id > 5))`,
+		},
+		{
+			name:  "单个字段降序",
+			sort:  []string{"id:desc"},
+			after: []interface{}{json.Number("5")},
+			want:  `((id < 5))`,
+		},
+		{
+			name:  "多个字段",
+			sort:  []string{"price:asc", "id:asc"},
+			after: []interface{}{json.Number("10"), json.Number("5")},
+			want:  `((price > 10) OR (price = 10 AND id > 5))`,
+		},
+		{
+			name:  "混合方向",
+			sort:  []string{"price:desc", "rank:asc", "id:asc"},
+			after: []interface{}{json.Number("1.5"), json.Number("-2"), json.Number("7")},
+			want:  `((price < 1.5) OR (price = 1.5 AND rank > -2) OR (price = 1.5 AND rank = -2 AND id > 7))`,
+		},
+		{
+			name:    "不支持的值类型",
+			sort:    []string{"id:asc"},
+			after:   []interface{}{[]interface{}{1}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sorts, err := parseCursorSort(tt.sort)
+			if err != nil {
+				t.Fatalf("parseCursorSort: %v", err)
+			}
+			got, err := keysetFilter(sorts, tt.after)
+			if tt.wantErr {
+				if !errors.Is(err, errInvalidSearchParams) {
+					t.Fatalf("keysetFilter() = %q, %v，期望 errInvalidSearchParams", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("keysetFilter() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("keysetFilter() = %q，期望 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCursorSort(t *testing.T) {
+	tests := []struct {
+		sort    string
+		wantErr bool
+	}{
+		{sort: "price:asc"},
+		{sort: "meta.rank:desc"},
+		{sort: "price", wantErr: true},
+		{sort: "price:up", wantErr: true},
+		{sort: "_geoPoint(0,0):asc", wantErr: true},
+		{sort: "price = 1 OR id:asc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		_, err := parseCursorSort([]string{tt.sort})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseCursorSort(%q) error = %v，期望出错 %v", tt.sort, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCursorNext(t *testing.T) {
+	cur := &searchCursor{IndexUID: "users", Sort: []string{"price:asc", "id:asc"}, Limit: 2}
+
+	tests := []struct {
+		name      string
+		hits      []map[string]interface{}
+		limit     int
+		wantAfter []interface{}
+		wantEnd   bool
+		wantErr   error
+	}{
+		{
+			name:      "满页",
+			hits:      []map[string]interface{}{{"price": 1.0, "id": 1.0}, {"price": 2.5, "id": 3.0}},
+			limit:     2,
+			wantAfter: []interface{}{json.Number("2.5"), json.Number("3")},
+		},
+		{
+			name:    "不满一页",
+			hits:    []map[string]interface{}{{"price": 1.0, "id": 1.0}},
+			limit:   2,
+			wantEnd: true,
+		},
+		{
+			name:    "缺少排序值",
+			hits:    []map[string]interface{}{{"price": 1.0, "id": 1.0}, {"id": 3.0}},
+			limit:   2,
+			wantErr: errCursorUnavailable,
+		},
+		{
+			name:    "排序值不是数字",
+			hits:    []map[string]interface{}{{"price": 1.0, "id": 1.0}, {"price": true, "id": 3.0}},
+			limit:   2,
+			wantErr: errCursorUnavailable,
+		},
+		{
+			name:    "字符串排序值",
+			hits:    []map[string]interface{}{{"price": 1.0, "id": "a"}, {"price": 2.0, "id": "b"}},
+			limit:   2,
+			wantErr: errCursorString,
+		},
+		{
+			name:    "不满一页的字符串主键",
+			hits:    []map[string]interface{}{{"price": 1.0, "id": "a"}},
+			limit:   2,
+			wantErr: errCursorString,
+		},
+		{
+			name:    "超出精确范围的整数",
+			hits:    []map[string]interface{}{{"price": 1.0, "id": 1.0}, {"price": 1.0, "id": float64(1 << 60)}},
+			limit:   2,
+			wantErr: errCursorUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, err := cur.next(tt.hits, tt.limit)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("next() = %q, %v，期望 %v", next, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("next() error = %v", err)
+			}
+			if tt.wantEnd {
+				if next != "" {
+					t.Fatalf("next() = %q，期望没有下一页", next)
+				}
+				return
+			}
+
+			decoded, err := decodeCursor(next)
+			if err != nil {
+				t.Fatalf("decodeCursor: %v", err)
+			}
+			if len(decoded.After) != len(tt.wantAfter) {
+				t.Fatalf("After = %v，期望 %v", decoded.After, tt.wantAfter)
+			}
+			for i := range tt.wantAfter {
+				if decoded.After[i] != tt.wantAfter[i] {
+					t.Errorf("After[%d] = %v，期望 %v", i, decoded.After[i], tt.wantAfter[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeCursorRejectsNonNumbers(t *testing.T) {
+	cur := searchCursor{IndexUID: "users", Sort: []string{"id:asc"}, Limit: 2, After: []interface{}{`1) OR (id > 0`}}
+	data, err := json.Marshal(cur)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decodeCursor(base64.RawURLEncoding.EncodeToString(data)); !errors.Is(err, errInvalidSearchParams) {
+		t.Fatalf("decodeCursor() error = %v，期望 errInvalidSearchParams", err)
+	}
+}
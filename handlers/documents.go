@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"meili_dog/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/meilisearch/meilisearch-go"
+)
+
+// AddDocuments 批量添加或替换文档
+func (h *SearchHandler) AddDocuments(c *gin.Context) {
+	h.writeDocuments(c, "添加", "add_documents", func(index *meilisearch.Index, documents []map[string]interface{}, primaryKey ...string) (*meilisearch.TaskInfo, error) {
+		return index.AddDocuments(documents, primaryKey...)
+	})
+}
+
+// UpdateDocuments 批量部分更新文档，只覆盖请求中出现的字段
+func (h *SearchHandler) UpdateDocuments(c *gin.Context) {
+	h.writeDocuments(c, "更新", "update_documents", func(index *meilisearch.Index, documents []map[string]interface{}, primaryKey ...string) (*meilisearch.TaskInfo, error) {
+		return index.UpdateDocuments(documents, primaryKey...)
+	})
+}
+
+// writeDocuments 添加和更新文档共用的处理流程
+func (h *SearchHandler) writeDocuments(c *gin.Context, action, operation string, write func(index *meilisearch.Index, documents []map[string]interface{}, primaryKey ...string) (*meilisearch.TaskInfo, error)) {
+	st := h.state()
+	if err := st.checkTenantWrite(c); err != nil {
+		c.JSON(tenantErrorStatus(err), models.DocumentResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	var documents []map[string]interface{}
+	if err := c.ShouldBindJSON(&documents); err != nil {
+		c.JSON(http.StatusBadRequest, models.DocumentResponse{
+			Success: false,
+			Error:   "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if len(documents) == 0 {
+		c.JSON(http.StatusBadRequest, models.DocumentResponse{
+			Success: false,
+			Error:   "文档列表不能为空",
+		})
+		return
+	}
+
+	indexUID, err := st.resolveIndexUID(c)
+	if err != nil {
+		c.JSON(indexErrorStatus(err, http.StatusBadRequest), models.DocumentResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	var primaryKey []string
+	if pk := c.Query("primary_key"); pk != "" {
+		primaryKey = append(primaryKey, pk)
+	}
+
+	call := startUpstream(c.Request.Context(), operation)
+	task, err := write(st.client.Index(indexUID), documents, primaryKey...)
+	call.end(err)
+	if err != nil {
+		logError(c, action+"文档错误", err, "index", indexUID)
+		c.JSON(http.StatusInternalServerError, models.DocumentResponse{
+			Success: false,
+			Error:   action + "失败: " + err.Error(),
+		})
+		return
+	}
+	h.invalidateSearchCache(indexUID, task.TaskUID)
+
+	state, err := h.waitTaskIfRequested(c, st, task.TaskUID)
+	if err != nil {
+		c.JSON(taskErrorStatus(err), models.DocumentResponse{
+			Success: false,
+			TaskUID: task.TaskUID,
+			Task:    state,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.DocumentResponse{
+		Success: true,
+		Message: fmt.Sprintf("已提交%s %d 个文档", action, len(documents)),
+		TaskUID: task.TaskUID,
+		Task:    state,
+	})
+}
+
+// DeleteDocuments 按ID列表或过滤条件删除文档
+func (h *SearchHandler) DeleteDocuments(c *gin.Context) {
+	st := h.state()
+
+	// 按 json.Number 解码，超过 2^53 的整数ID不经过 float64，避免删错文档
+	var req models.DocumentDeleteRequest
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.DocumentResponse{
+			Success: false,
+			Error:   "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	hasIDs := len(req.IDs) > 0
+	hasFilter := req.Filter != nil || len(req.Filters) > 0
+	if hasIDs == hasFilter {
+		c.JSON(http.StatusBadRequest, models.DocumentResponse{
+			Success: false,
+			Error:   "ids 与 filter/filters 必须且只能设置一个",
+		})
+		return
+	}
+
+	indexUID, err := st.resolveIndexUID(c)
+	if err != nil {
+		c.JSON(indexErrorStatus(err, http.StatusBadRequest), models.DocumentResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	index := st.client.Index(indexUID)
+
+	// 租户调用方只能删除本租户的文档
+	tenantFilter, err := st.tenantFilter(c)
+	if err == nil && hasIDs && tenantFilter != "" {
+		err = errTenantDocumentWrite
+	}
+	if err != nil {
+		c.JSON(tenantErrorStatus(err), models.DocumentResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	var (
+		task *meilisearch.TaskInfo
+		call *upstreamCall
+	)
+	if hasIDs {
+		ids, idErr := documentIDs(req.IDs)
+		if idErr != nil {
+			c.JSON(http.StatusBadRequest, models.DocumentResponse{
+				Success: false,
+				Error:   idErr.Error(),
+			})
+			return
+		}
+		call = startUpstream(c.Request.Context(), "delete_documents")
+		task, err = index.DeleteDocuments(ids)
+	} else {
+		filter, filterErr := h.buildFilter(c.Request.Context(), st, indexUID, req.Filters, req.Filter)
+		if filterErr != nil {
+			c.JSON(filterErrorStatus(filterErr), models.DocumentResponse{
+				Success: false,
+				Error:   filterErr.Error(),
+			})
+			return
+		}
+		call = startUpstream(c.Request.Context(), "delete_documents")
+		task, err = index.DeleteDocumentsByFilter(andFilters(tenantFilter, filter))
+	}
+	call.end(err)
+	if err != nil {
+		logError(c, "删除文档错误", err, "index", indexUID)
+		c.JSON(http.StatusInternalServerError, models.DocumentResponse{
+			Success: false,
+			Error:   "删除失败: " + err.Error(),
+		})
+		return
+	}
+	h.invalidateSearchCache(indexUID, task.TaskUID)
+
+	state, err := h.waitTaskIfRequested(c, st, task.TaskUID)
+	if err != nil {
+		c.JSON(taskErrorStatus(err), models.DocumentResponse{
+			Success: false,
+			TaskUID: task.TaskUID,
+			Task:    state,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.DocumentResponse{
+		Success: true,
+		Message: "已提交删除文档",
+		TaskUID: task.TaskUID,
+		Task:    state,
+	})
+}
+
+// documentIDs 将文档ID统一转换为字符串，支持字符串和数字ID
+func documentIDs(values []interface{}) ([]string, error) {
+	ids := make([]string, 0, len(values))
+	for _, value := range values {
+		switch v := value.(type) {
+		case string:
+			if v == "" {
+				return nil, fmt.Errorf("文档ID不能为空")
+			}
+			ids = append(ids, v)
+		case json.Number:
+			// Meilisearch 的数字ID是非负整数，原样保留数字文本
+			if _, err := strconv.ParseUint(v.String(), 10, 64); err != nil {
+				return nil, fmt.Errorf("数字文档ID必须是非负整数: %s", v)
+			}
+			ids = append(ids, v.String())
+		default:
+			return nil, fmt.Errorf("不支持的文档ID类型: %T", value)
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"meili_dog/models"
+)
+
+func TestDocumentIDs(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "字符串ID", body: `{"ids":["a-1","b_2"]}`, want: []string{"a-1", "b_2"}},
+		{name: "数字ID", body: `{"ids":[1,42]}`, want: []string{"1", "42"}},
+		{name: "超过 2^53 的整数", body: `{"ids":[9007199254740993,18446744073709551615]}`, want: []string{"9007199254740993", "18446744073709551615"}},
+		{name: "空字符串", body: `{"ids":[""]}`, wantErr: true},
+		{name: "小数", body: `{"ids":[1.5]}`, wantErr: true},
+		{name: "负数", body: `{"ids":[-1]}`, wantErr: true},
+		{name: "科学计数法", body: `{"ids":[1e3]}`, wantErr: true},
+		{name: "对象", body: `{"ids":[{"id":1}]}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req models.DocumentDeleteRequest
+			decoder := json.NewDecoder(strings.NewReader(tt.body))
+			decoder.UseNumber()
+			if err := decoder.Decode(&req); err != nil {
+				t.Fatal(err)
+			}
+			got, err := documentIDs(req.IDs)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("documentIDs() = %v，期望出错", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("documentIDs() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("documentIDs() = %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
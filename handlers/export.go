@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"meili_dog/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/meilisearch/meilisearch-go"
+)
+
+// exportBatchSize 导出时每次从 Meilisearch 读取的文档数
+const exportBatchSize = 1000
+
+// exportTotalHeader 响应头，开始导出时满足条件的文档数，用于校验导出是否完整
+const exportTotalHeader = "X-Export-Total"
+
+// ExportDocuments 以 NDJSON 或 CSV 流式导出满足过滤条件的所有文档
+// 使用 Meilisearch 文档接口分批读取，不受搜索的 maxTotalHits 限制；开始写入后出错只能中断导出
+// 文档接口只支持 offset 翻页，导出期间写入或删除文档会使后续批次的位置移动，可能重复或遗漏文档，
+// 需要一致的导出时应暂停写入，或用导出开始前的时间等不会变化的条件过滤
+func (h *SearchHandler) ExportDocuments(c *gin.Context) {
+	st := h.state()
+
+	var req models.ExportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	format := strings.ToLower(req.Format)
+	switch format {
+	case "":
+		format = ImportFormatNDJSON
+	case ImportFormatNDJSON, ImportFormatCSV:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("不支持的导出格式 %q，可选 ndjson、csv", req.Format)})
+		return
+	}
+
+	indexUID, err := st.resolveIndexUID(c)
+	if err != nil {
+		c.JSON(indexErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	filterExpr, err := parseFilterParam(req.Filter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter, err := h.buildFilter(c.Request.Context(), st, indexUID, queryFilters(c), filterExpr)
+	if err != nil {
+		c.JSON(filterErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	tenantFilter, err := st.tenantFilter(c)
+	if err != nil {
+		c.JSON(tenantErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	filter = andFilters(tenantFilter, filter)
+
+	var fields []string
+	for _, field := range strings.Split(req.Fields, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	// CSV 的列必须事先确定，各文档的字段可能不同，不能按第一个文档推断
+	if format == ImportFormatCSV && len(fields) == 0 {
+		fieldSchema := st.schemas[indexUID]
+		if fieldSchema == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "索引没有配置字段类型，CSV 导出需要通过 fields 指定导出的字段"})
+			return
+		}
+		fields = fieldSchema.Fields()
+	}
+
+	index := st.client.Index(indexUID)
+	fetch := func(offset int64) (*meilisearch.DocumentsResult, error) {
+		query := &meilisearch.DocumentsQuery{Offset: offset, Limit: exportBatchSize, Fields: fields}
+		if filter != "" {
+			query.Filter = filter
+		}
+		var result meilisearch.DocumentsResult
+		call := startUpstream(c.Request.Context(), "get_documents")
+		err := index.GetDocuments(query, &result)
+		call.end(err)
+		return &result, err
+	}
+
+	// 第一批读取失败时还能返回错误响应
+	batch, err := fetch(0)
+	if err != nil {
+		logError(c, "导出文档错误", err, "index", indexUID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "导出失败: " + err.Error()})
+		return
+	}
+
+	contentType := "application/x-ndjson"
+	if format == ImportFormatCSV {
+		contentType = "text/csv; charset=utf-8"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="`+indexUID+"."+format+`"`)
+	c.Header(exportTotalHeader, strconv.FormatInt(batch.Total, 10))
+	c.Status(http.StatusOK)
+
+	buffered := bufio.NewWriter(c.Writer)
+	writer := newExportWriter(format, buffered, fields)
+	var exported int64
+	for {
+		for _, doc := range batch.Results {
+			if err := writer.write(doc); err != nil {
+				logError(c, "导出文档中断", err, "index", indexUID, "exported", exported)
+				return
+			}
+			exported++
+		}
+		if err := writer.flush(); err != nil {
+			logError(c, "导出文档中断", err, "index", indexUID, "exported", exported)
+			return
+		}
+		if err := buffered.Flush(); err != nil {
+			logError(c, "导出文档中断", err, "index", indexUID, "exported", exported)
+			return
+		}
+		c.Writer.Flush()
+
+		if len(batch.Results) < exportBatchSize {
+			break
+		}
+		if err := c.Request.Context().Err(); err != nil {
+			slog.InfoContext(c.Request.Context(), "客户端断开，停止导出", "index", indexUID, "exported", exported)
+			return
+		}
+		// 按已导出的文档数翻页，期间的写入会使位置移动
+		if batch, err = fetch(exported); err != nil {
+			logError(c, "导出文档中断", err, "index", indexUID, "exported", exported)
+			return
+		}
+	}
+
+	slog.InfoContext(c.Request.Context(), "导出文档", "index", indexUID, "format", format, "documents", exported)
+}
+
+// exportWriter 按导出格式写入文档
+type exportWriter struct {
+	ndjson  *json.Encoder
+	csv     *csv.Writer
+	columns []string // CSV 列
+	header  bool     // 是否已写入 CSV 表头
+}
+
+func newExportWriter(format string, w *bufio.Writer, fields []string) *exportWriter {
+	if format == ImportFormatCSV {
+		return &exportWriter{csv: csv.NewWriter(w), columns: fields}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return &exportWriter{ndjson: encoder}
+}
+
+func (w *exportWriter) write(doc map[string]interface{}) error {
+	if w.ndjson != nil {
+		return w.ndjson.Encode(doc)
+	}
+
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+
+	record := make([]string, len(w.columns))
+	for i, field := range w.columns {
+		value, err := csvValue(documentValue(doc, field))
+		if err != nil {
+			return err
+		}
+		record[i] = value
+	}
+	return w.csv.Write(record)
+}
+
+// flush 写出缓冲的内容，没有满足条件的文档时 CSV 也包含表头
+func (w *exportWriter) flush() error {
+	if w.csv == nil {
+		return nil
+	}
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+func (w *exportWriter) writeHeader() error {
+	if w.header {
+		return nil
+	}
+	if err := w.csv.Write(w.columns); err != nil {
+		return err
+	}
+	w.header = true
+	return nil
+}
+
+// documentValue 按点号路径读取文档中的字段，不存在时返回 nil
+func documentValue(doc map[string]interface{}, field string) interface{} {
+	if value, ok := doc[field]; ok {
+		return value
+	}
+	var value interface{} = doc
+	for _, part := range strings.Split(field, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[part]
+	}
+	return value
+}
+
+// csvValue 转换为 CSV 单元格，数组和对象使用 JSON
+func csvValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case float64, bool:
+		return toString(v), nil
+	default:
+		data, err := json.Marshal(v)
+		return string(data), err
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"meili_dog/models"
+	"meili_dog/schema"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchFacetValues 在分面字段的取值中搜索，用于分面过滤的自动补全
+// 会带上当前的搜索词和过滤条件，只返回满足条件的文档中的取值
+func (h *SearchHandler) SearchFacetValues(c *gin.Context) {
+	st := h.state()
+
+	var req models.FacetSearchRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	indexUID, err := st.resolveIndexUID(c)
+	if err != nil {
+		c.JSON(indexErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := checkQueryLength(req.Query, st.defaults); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	facet := c.Param("facet")
+	if facet == "*" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "分面字段不能为 *"})
+		return
+	}
+	if err := h.validateFacets(c.Request.Context(), st, indexUID, []string{facet}); err != nil {
+		c.JSON(filterErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	filterExpr, err := parseFilterParam(req.Filter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter, err := h.buildFilter(c.Request.Context(), st, indexUID, queryFilters(c), filterExpr)
+	if err != nil {
+		c.JSON(filterErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	tenantFilter, err := st.tenantFilter(c)
+	if err != nil {
+		c.JSON(tenantErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	filter = andFilters(tenantFilter, filter)
+
+	body := map[string]interface{}{
+		"facetName":  facet,
+		"facetQuery": req.FacetQuery,
+		"q":          req.Query,
+	}
+	if filter != "" {
+		body["filter"] = filter
+	}
+	if opt := st.indexes[indexUID]; len(opt.AttributesToSearchOn) > 0 {
+		body["attributesToSearchOn"] = opt.AttributesToSearchOn
+	}
+
+	var result struct {
+		FacetHits        []models.FacetHit `json:"facetHits"`
+		FacetQuery       string            `json:"facetQuery"`
+		ProcessingTimeMs int               `json:"processingTimeMs"`
+	}
+	path := "/indexes/" + url.PathEscape(indexUID) + "/facet-search"
+	if err := h.doRequest(c.Request.Context(), st, http.MethodPost, path, body, &result); err != nil {
+		logError(c, "分面值搜索错误", err, "index", indexUID)
+		c.JSON(upstreamErrorStatus(err), gin.H{"error": "分面值搜索失败: " + err.Error()})
+		return
+	}
+
+	if result.FacetHits == nil {
+		result.FacetHits = []models.FacetHit{}
+	}
+
+	c.JSON(http.StatusOK, models.FacetSearchResponse{
+		FacetHits:        result.FacetHits,
+		FacetName:        facet,
+		FacetQuery:       req.FacetQuery,
+		Query:            req.Query,
+		ProcessingTimeMs: result.ProcessingTimeMs,
+		IndexUID:         indexUID,
+	})
+}
+
+// normalizeFacets 拆分逗号分隔的分面字段并去重
+func normalizeFacets(facets []string) []string {
+	seen := make(map[string]bool, len(facets))
+	var result []string
+	for _, item := range facets {
+		for _, facet := range strings.Split(item, ",") {
+			facet = strings.TrimSpace(facet)
+			if facet == "" || seen[facet] {
+				continue
+			}
+			seen[facet] = true
+			result = append(result, facet)
+		}
+	}
+	return result
+}
+
+// validateFacets 校验分面字段必须是可过滤字段
+func (h *SearchHandler) validateFacets(ctx context.Context, st *handlerState, indexUID string, facets []string) error {
+	if len(facets) == 0 {
+		return nil
+	}
+
+	attrs, err := h.filterableAttributes(ctx, st, indexUID)
+	if err != nil {
+		return err
+	}
+
+	for _, facet := range facets {
+		if facet == "*" {
+			continue
+		}
+		if !schema.FieldNamePattern.MatchString(facet) || !hasAttribute(facet, attrs) {
+			return fmt.Errorf("%w: 分面字段不可过滤: %s", errInvalidFilter, facet)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,368 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"meili_dog/models"
+	"meili_dog/schema"
+	"meili_dog/tracing"
+)
+
+// maxFilterDepth 过滤表达式最大嵌套深度
+const maxFilterDepth = 16
+
+// attributeCacheTTL 可过滤、可排序字段缓存时间
+const attributeCacheTTL = 30 * time.Second
+
+var errInvalidFilter = errors.New("过滤条件无效")
+
+// filterRules 校验过滤条件所需的字段信息
+type filterRules struct {
+	filterable []string       // Meilisearch 中的可过滤字段
+	schema     *schema.Schema // 配置的字段类型，未配置时为 nil
+}
+
+// attributeCache 可过滤或可排序字段缓存，避免每次搜索都请求 Meilisearch
+type attributeCache struct {
+	mu      sync.Mutex
+	entries map[string]attributeEntry
+}
+
+type attributeEntry struct {
+	attributes []string
+	expiresAt  time.Time
+}
+
+func newAttributeCache() *attributeCache {
+	return &attributeCache{entries: make(map[string]attributeEntry)}
+}
+
+func (fc *attributeCache) get(indexUID string) ([]string, bool) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	entry, ok := fc.entries[indexUID]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.attributes, true
+}
+
+func (fc *attributeCache) set(indexUID string, attributes []string) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	fc.entries[indexUID] = attributeEntry{
+		attributes: attributes,
+		expiresAt:  time.Now().Add(attributeCacheTTL),
+	}
+}
+
+// invalidate 删除索引的缓存，indexUID 为空时清空缓存
+func (fc *attributeCache) invalidate(indexUID string) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if indexUID == "" {
+		clear(fc.entries)
+		return
+	}
+	delete(fc.entries, indexUID)
+}
+
+// filterableAttributes 获取索引的可过滤字段
+func (h *SearchHandler) filterableAttributes(ctx context.Context, st *handlerState, indexUID string) ([]string, error) {
+	if attrs, ok := h.filterable.get(indexUID); ok {
+		return attrs, nil
+	}
+
+	call := startUpstream(ctx, "get_filterable_attributes")
+	attrs, err := st.client.Index(indexUID).GetFilterableAttributes()
+	call.end(err)
+	if err != nil {
+		return nil, fmt.Errorf("获取可过滤字段失败: %w", err)
+	}
+
+	result := derefStringSlice(attrs)
+	h.filterable.set(indexUID, result)
+	return result, nil
+}
+
+// sortableAttributes 获取索引的可排序字段
+func (h *SearchHandler) sortableAttributes(ctx context.Context, st *handlerState, indexUID string) ([]string, error) {
+	if attrs, ok := h.sortable.get(indexUID); ok {
+		return attrs, nil
+	}
+
+	call := startUpstream(ctx, "get_sortable_attributes")
+	attrs, err := st.client.Index(indexUID).GetSortableAttributes()
+	call.end(err)
+	if err != nil {
+		return nil, fmt.Errorf("获取可排序字段失败: %w", err)
+	}
+
+	result := derefStringSlice(attrs)
+	h.sortable.set(indexUID, result)
+	return result, nil
+}
+
+// invalidateAttributes 修改设置后删除索引的可过滤、可排序字段缓存，indexUID 为空时清空缓存
+func (h *SearchHandler) invalidateAttributes(indexUID string) {
+	h.filterable.invalidate(indexUID)
+	h.sortable.invalidate(indexUID)
+}
+
+// parseFilterParam 解析查询参数中的 JSON 过滤表达式
+func parseFilterParam(raw string) (*models.FilterNode, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var node models.FilterNode
+	if err := json.Unmarshal([]byte(raw), &node); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidFilter, err)
+	}
+	return &node, nil
+}
+
+// buildFilter 合并简单过滤条件和过滤表达式，校验后生成 Meilisearch 过滤字符串
+// 没有任何过滤条件时返回空字符串
+func (h *SearchHandler) buildFilter(ctx context.Context, st *handlerState, indexUID string, filters map[string]interface{}, expr *models.FilterNode) (filter string, err error) {
+	ctx, span := tracing.Start(ctx, "build_filter")
+	defer func() { tracing.End(span, err) }()
+
+	var nodes []models.FilterNode
+	if len(filters) > 0 {
+		nodes = append(nodes, filtersToNodes(filters)...)
+	}
+	if expr != nil {
+		nodes = append(nodes, *expr)
+	}
+	if len(nodes) == 0 {
+		return "", nil
+	}
+
+	root := nodes[0]
+	if len(nodes) > 1 {
+		root = models.FilterNode{And: nodes}
+	}
+
+	attrs, err := h.filterableAttributes(ctx, st, indexUID)
+	if err != nil {
+		return "", err
+	}
+
+	return renderFilter(&root, filterRules{filterable: attrs, schema: st.schemas[indexUID]}, 0)
+}
+
+// filtersToNodes 将 filters 简单键值条件转换为过滤节点
+// 标量值生成 eq 条件，数组生成 in 条件
+func filtersToNodes(filters map[string]interface{}) []models.FilterNode {
+	fields := make([]string, 0, len(filters))
+	for field := range filters {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var nodes []models.FilterNode
+	for _, field := range fields {
+		value := filters[field]
+		if values, ok := value.([]interface{}); ok {
+			if len(values) == 0 {
+				continue
+			}
+			nodes = append(nodes, models.FilterNode{Field: field, Op: models.FilterOpIn, Value: values})
+			continue
+		}
+		nodes = append(nodes, models.FilterNode{Field: field, Op: models.FilterOpEq, Value: value})
+	}
+	return nodes
+}
+
+// renderFilter 递归渲染过滤节点
+func renderFilter(node *models.FilterNode, rules filterRules, depth int) (string, error) {
+	if depth > maxFilterDepth {
+		return "", fmt.Errorf("%w: 嵌套层级超过 %d", errInvalidFilter, maxFilterDepth)
+	}
+
+	kinds := 0
+	if node.And != nil {
+		kinds++
+	}
+	if node.Or != nil {
+		kinds++
+	}
+	if node.Not != nil {
+		kinds++
+	}
+	if node.Field != "" || node.Op != "" {
+		kinds++
+	}
+	if kinds != 1 {
+		return "", fmt.Errorf("%w: 每个节点只能是 and/or/not 或单个条件之一", errInvalidFilter)
+	}
+
+	switch {
+	case node.And != nil:
+		return renderGroup(node.And, " AND ", rules, depth)
+	case node.Or != nil:
+		return renderGroup(node.Or, " OR ", rules, depth)
+	case node.Not != nil:
+		inner, err := renderFilter(node.Not, rules, depth+1)
+		if err != nil {
+			return "", err
+		}
+		return "NOT (" + inner + ")", nil
+	default:
+		return renderCondition(node, rules)
+	}
+}
+
+// renderGroup 渲染 and/or 分组
+func renderGroup(children []models.FilterNode, sep string, rules filterRules, depth int) (string, error) {
+	if len(children) == 0 {
+		return "", fmt.Errorf("%w: and/or 不能为空", errInvalidFilter)
+	}
+
+	parts := make([]string, 0, len(children))
+	for i := range children {
+		part, err := renderFilter(&children[i], rules, depth+1)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, part)
+	}
+
+	if len(parts) == 1 {
+		return parts[0], nil
+	}
+	return "(" + strings.Join(parts, sep) + ")", nil
+}
+
+// renderCondition 渲染单个条件，配置了字段类型时拒绝未声明的字段并转换值的类型
+func renderCondition(node *models.FilterNode, rules filterRules) (string, error) {
+	field := node.Field
+	if !schema.FieldNamePattern.MatchString(field) {
+		return "", fmt.Errorf("%w: 字段名不合法: %q", errInvalidFilter, field)
+	}
+	if rules.schema != nil {
+		if _, ok := rules.schema.FieldType(field); !ok {
+			return "", fmt.Errorf("%w: 未知字段: %s", errInvalidFilter, field)
+		}
+	}
+	if !hasAttribute(field, rules.filterable) {
+		return "", fmt.Errorf("%w: 字段不可过滤: %s", errInvalidFilter, field)
+	}
+
+	switch node.Op {
+	case models.FilterOpEq, models.FilterOpNeq, models.FilterOpGt, models.FilterOpGte, models.FilterOpLt, models.FilterOpLte:
+		value, err := rules.renderValue(field, node.Value)
+		if err != nil {
+			return "", err
+		}
+		return field + " " + comparisonOperators[node.Op] + " " + value, nil
+	case models.FilterOpIn:
+		values, ok := node.Value.([]interface{})
+		if !ok || len(values) == 0 {
+			return "", fmt.Errorf("%w: in 操作符需要非空数组: %s", errInvalidFilter, field)
+		}
+		rendered := make([]string, 0, len(values))
+		for _, v := range values {
+			value, err := rules.renderValue(field, v)
+			if err != nil {
+				return "", err
+			}
+			rendered = append(rendered, value)
+		}
+		return field + " IN [" + strings.Join(rendered, ", ") + "]", nil
+	case models.FilterOpExists:
+		return field + " EXISTS", nil
+	case models.FilterOpIsNull:
+		return field + " IS NULL", nil
+	case models.FilterOpIsEmpty:
+		return field + " IS EMPTY", nil
+	case models.FilterOpTo:
+		from, err := rules.renderValue(field, node.Value)
+		if err != nil {
+			return "", err
+		}
+		to, err := rules.renderValue(field, node.To)
+		if err != nil {
+			return "", err
+		}
+		return field + " " + from + " TO " + to, nil
+	default:
+		return "", fmt.Errorf("%w: 不支持的操作符: %q", errInvalidFilter, node.Op)
+	}
+}
+
+var comparisonOperators = map[string]string{
+	models.FilterOpEq:  "=",
+	models.FilterOpNeq: "!=",
+	models.FilterOpGt:  ">",
+	models.FilterOpGte: ">=",
+	models.FilterOpLt:  "<",
+	models.FilterOpLte: "<=",
+}
+
+// renderValue 按字段类型转换后渲染过滤值
+func (r filterRules) renderValue(field string, value interface{}) (string, error) {
+	if r.schema != nil {
+		coerced, err := r.schema.Coerce(field, value)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", errInvalidFilter, err)
+		}
+		value = coerced
+	}
+	return renderFilterValue(value)
+}
+
+// renderFilterValue 渲染过滤值，字符串统一使用双引号并转义
+func renderFilterValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return quoteFilterString(v)
+	case int, int32, int64, float32, float64, bool:
+		return toString(v), nil
+	case json.Number:
+		return v.String(), nil
+	default:
+		return "", fmt.Errorf("%w: 不支持的值类型: %T", errInvalidFilter, value)
+	}
+}
+
+// quoteFilterString 用双引号包裹字符串并转义其中的双引号，防止值中的引号截断表达式
+// Meilisearch 只把 \" 还原为引号，其他反斜杠原样保留，但反斜杠总会跳过后一个字符：
+// 以反斜杠结尾或包含 \" 的值会让引号失去作用，无法安全表示，返回错误
+func quoteFilterString(s string) (string, error) {
+	if strings.HasSuffix(s, `\`) || strings.Contains(s, `\"`) {
+		return "", fmt.Errorf("%w: 字符串值不能以反斜杠结尾，也不能包含反斜杠加双引号: %q", errInvalidFilter, s)
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`, nil
+}
+
+// hasAttribute 判断字段是否在可过滤或可排序字段中，列出字段的子字段同样包含在内
+func hasAttribute(field string, attrs []string) bool {
+	for _, attr := range attrs {
+		if attr == "*" || attr == field || strings.HasPrefix(field, attr+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// filterErrorStatus 过滤条件或搜索参数错误返回 400，获取可过滤字段失败返回 500
+func filterErrorStatus(err error) int {
+	if errors.Is(err, errInvalidFilter) || errors.Is(err, errInvalidSearchParams) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+
+	"meili_dog/models"
+	"meili_dog/schema"
+)
+
+func TestRenderFilter(t *testing.T) {
+	fieldSchema, err := schema.New(&models.FieldConfig{
+		NumberFields: models.FieldGroup{Names: []string{"id", "price"}},
+		StringFields: models.FieldGroup{Names: []string{"genre"}},
+		ObjectFields: models.FieldGroup{Names: []string{"meta"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		filter  string
+		schema  *schema.Schema
+		want    string
+		wantErr bool
+	}{
+		{name: "eq 字符串", filter: `{"field":"genre","op":"eq","value":"Action"}`, want: `genre = "Action"`},
+		{name: "eq 数字", filter: `{"field":"price","op":"gt","value":10}`, want: `price > 10`},
+		{name: "in", filter: `{"field":"genre","op":"in","value":["a","b"]}`, want: `genre IN ["a", "b"]`},
+		{name: "to", filter: `{"field":"price","op":"to","value":1,"to":5}`, want: `price 1 TO 5`},
+		{name: "exists", filter: `{"field":"genre","op":"exists"}`, want: `genre EXISTS`},
+		{name: "嵌套字段", filter: `{"field":"meta.k","op":"eq","value":1}`, want: `meta.k = 1`},
+		{
+			name:   "and/or/not",
+			filter: `{"and":[{"field":"genre","op":"eq","value":"a"},{"or":[{"field":"price","op":"lt","value":1},{"not":{"field":"id","op":"eq","value":2}}]}]}`,
+			want:   `(genre = "a" AND (price < 1 OR NOT (id = 2)))`,
+		},
+		{name: "转义双引号", filter: `{"field":"genre","op":"eq","value":"a\" OR id = 1"}`, want: `genre = "a\" OR id = 1"`},
+		{name: "反斜杠原样保留", filter: `{"field":"genre","op":"eq","value":"a\\b"}`, want: `genre = "a\b"`},
+		{name: "以反斜杠结尾", filter: `{"field":"genre","op":"eq","value":"a\\"}`, wantErr: true},
+		{name: "反斜杠加双引号", filter: `{"field":"genre","op":"eq","value":"a\\\" OR id = 1"}`, wantErr: true},
+		{name: "字段不可过滤", filter: `{"field":"name","op":"eq","value":"a"}`, wantErr: true},
+		{name: "字段名不合法", filter: `{"field":"genre = 1 OR id","op":"eq","value":"a"}`, wantErr: true},
+		{name: "未知操作符", filter: `{"field":"genre","op":"like","value":"a"}`, wantErr: true},
+		{name: "空分组", filter: `{"and":[]}`, wantErr: true},
+		{name: "节点类型冲突", filter: `{"field":"genre","op":"eq","value":"a","not":{"field":"id","op":"exists"}}`, wantErr: true},
+		{name: "字段类型转换", filter: `{"field":"price","op":"eq","value":"2"}`, schema: fieldSchema, want: `price = 2`},
+		{name: "字段类型不匹配", filter: `{"field":"price","op":"eq","value":"abc"}`, schema: fieldSchema, wantErr: true},
+		{name: "未声明的字段", filter: `{"field":"tenant_id","op":"eq","value":"a"}`, schema: fieldSchema, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := parseFilterParam(tt.filter)
+			if err != nil {
+				t.Fatalf("parseFilterParam: %v", err)
+			}
+			rules := filterRules{filterable: []string{"genre", "price", "id", "meta", "tenant_id"}, schema: tt.schema}
+			got, err := renderFilter(node, rules, 0)
+			if tt.wantErr {
+				if !errors.Is(err, errInvalidFilter) {
+					t.Fatalf("renderFilter() = %q, %v，期望 errInvalidFilter", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("renderFilter() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("renderFilter() = %q，期望 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderFilterDepth(t *testing.T) {
+	node := &models.FilterNode{Field: "genre", Op: models.FilterOpExists}
+	for i := 0; i <= maxFilterDepth+1; i++ {
+		node = &models.FilterNode{Not: node}
+	}
+	if _, err := renderFilter(node, filterRules{filterable: []string{"*"}}, 0); !errors.Is(err, errInvalidFilter) {
+		t.Fatalf("嵌套过深时期望 errInvalidFilter，实际 %v", err)
+	}
+}
+
+func TestQuoteFilterString(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "", want: `""`},
+		{value: "abc", want: `"abc"`},
+		{value: `a"b`, want: `"a\"b"`},
+		{value: `a\b`, want: `"a\b"`},
+		{value: `a\\b`, want: `"a\\b"`},
+		{value: `a\`, wantErr: true},
+		{value: `a\"b`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := quoteFilterString(tt.value)
+		if tt.wantErr {
+			if !errors.Is(err, errInvalidFilter) {
+				t.Errorf("quoteFilterString(%q) = %q, %v，期望 errInvalidFilter", tt.value, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("quoteFilterString(%q) = %q, %v，期望 %q", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestHasAttribute(t *testing.T) {
+	tests := []struct {
+		field string
+		attrs []string
+		want  bool
+	}{
+		{field: "genre", attrs: []string{"genre"}, want: true},
+		{field: "meta.k", attrs: []string{"meta"}, want: true},
+		{field: "metadata", attrs: []string{"meta"}, want: false},
+		{field: "anything", attrs: []string{"*"}, want: true},
+		{field: "genre", attrs: nil, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := hasAttribute(tt.field, tt.attrs); got != tt.want {
+			t.Errorf("hasAttribute(%q, %v) = %v，期望 %v", tt.field, tt.attrs, got, tt.want)
+		}
+	}
+}
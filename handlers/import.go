@@ -0,0 +1,441 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"meili_dog/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/meilisearch/meilisearch-go"
+)
+
+// 支持的导入格式
+const (
+	ImportFormatNDJSON = "ndjson"
+	ImportFormatCSV    = "csv"
+	ImportFormatJSON   = "json"
+)
+
+// defaultImportBatchSize 未配置 import.batch_size 时每批写入的文档数
+const defaultImportBatchSize = 1000
+
+// ImportOptions 批量导入参数
+type ImportOptions struct {
+	Format       string
+	BatchSize    int
+	PrimaryKey   string
+	CSVDelimiter rune
+}
+
+// DetectImportFormat 根据 Content-Type 或文件扩展名推断导入格式
+func DetectImportFormat(contentType, filename string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		switch mediaType {
+		case "application/x-ndjson", "application/ndjson", "application/jsonl":
+			return ImportFormatNDJSON
+		case "text/csv":
+			return ImportFormatCSV
+		case "application/json":
+			return ImportFormatJSON
+		}
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".ndjson", ".jsonl":
+		return ImportFormatNDJSON
+	case ".csv":
+		return ImportFormatCSV
+	case ".json":
+		return ImportFormatJSON
+	}
+	return ""
+}
+
+// ImportDocuments 流式读取文档并按批次写入索引
+// 每次只在内存中保留一个批次；某个批次写入失败会记录后继续，数据格式错误则停止导入
+func (h *SearchHandler) ImportDocuments(ctx context.Context, indexUID string, r io.Reader, opts ImportOptions) (*models.ImportResult, error) {
+	st := h.state()
+	if err := st.checkIndexAllowed(indexUID); err != nil {
+		return nil, err
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = st.config.Import.BatchSize
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultImportBatchSize
+	}
+
+	importer := &documentImporter{
+		ctx:    ctx,
+		index:  st.client.Index(indexUID),
+		opts:   opts,
+		result: &models.ImportResult{IndexUID: indexUID, Format: opts.Format, Batches: []models.ImportBatch{}},
+	}
+
+	var err error
+	switch opts.Format {
+	case ImportFormatNDJSON:
+		err = importer.importNDJSON(r)
+	case ImportFormatCSV:
+		err = importer.importCSV(r)
+	case ImportFormatJSON:
+		err = importer.importJSON(r)
+	default:
+		err = fmt.Errorf("不支持的导入格式: %q，可选 ndjson、csv、json", opts.Format)
+	}
+
+	result := importer.result
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	// 同一索引的任务按顺序执行，最后一个批次完成时所有批次都已写入
+	for i := len(result.Batches) - 1; i >= 0; i-- {
+		if result.Batches[i].Error == "" {
+			h.invalidateSearchCache(indexUID, result.Batches[i].TaskUID)
+			break
+		}
+	}
+	result.Success = err == nil && result.FailedBatches == 0
+	return result, err
+}
+
+// documentImporter 保存单次导入的状态
+type documentImporter struct {
+	ctx    context.Context // 日志使用的上下文
+	index  *meilisearch.Index
+	opts   ImportOptions
+	result *models.ImportResult
+}
+
+func (im *documentImporter) primaryKey() []string {
+	if im.opts.PrimaryKey == "" {
+		return nil
+	}
+	return []string{im.opts.PrimaryKey}
+}
+
+// sendBatch 写入一个批次并记录任务UID或错误
+func (im *documentImporter) sendBatch(documents int, send func() (*meilisearch.TaskInfo, error)) {
+	batch := models.ImportBatch{
+		Batch:     len(im.result.Batches) + 1,
+		Documents: documents,
+	}
+
+	call := startUpstream(im.ctx, "import_documents")
+	task, err := send()
+	call.end(err)
+	if err != nil {
+		slog.ErrorContext(im.ctx, "导入文档错误", append([]any{"index", im.index.UID, "batch", batch.Batch}, errorAttrs(err)...)...)
+		batch.Error = err.Error()
+		im.result.FailedBatches++
+	} else {
+		batch.TaskUID = task.TaskUID
+	}
+
+	im.result.TotalDocuments += documents
+	im.result.Batches = append(im.result.Batches, batch)
+}
+
+// importNDJSON 按行读取 NDJSON，每行一个文档
+func (im *documentImporter) importNDJSON(r io.Reader) error {
+	reader := bufio.NewReader(r)
+	var (
+		buf   bytes.Buffer
+		count int
+		line  int
+	)
+
+	flush := func() {
+		if count == 0 {
+			return
+		}
+		im.sendBatch(count, func() (*meilisearch.TaskInfo, error) {
+			return im.index.AddDocumentsNdjson(buf.Bytes(), im.primaryKey()...)
+		})
+		buf.Reset()
+		count = 0
+	}
+
+	for {
+		raw, err := reader.ReadBytes('\n')
+		if len(raw) > 0 {
+			line++
+			doc := bytes.TrimSpace(raw)
+			if len(doc) > 0 {
+				if doc[0] != '{' || !json.Valid(doc) {
+					return fmt.Errorf("第 %d 行不是合法的 JSON 对象", line)
+				}
+				buf.Write(doc)
+				buf.WriteByte('\n')
+				count++
+				if count >= im.opts.BatchSize {
+					flush()
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("读取第 %d 行失败: %w", line+1, err)
+		}
+	}
+
+	flush()
+	return nil
+}
+
+// importCSV 读取带表头的 CSV，每个批次重新带上表头
+func (im *documentImporter) importCSV(r io.Reader) error {
+	reader := csv.NewReader(r)
+	if im.opts.CSVDelimiter != 0 {
+		reader.Comma = im.opts.CSVDelimiter
+	}
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取 CSV 表头失败: %w", err)
+	}
+	header = append([]string(nil), header...)
+
+	var (
+		buf    bytes.Buffer
+		writer *csv.Writer
+		count  int
+	)
+
+	reset := func() error {
+		buf.Reset()
+		writer = csv.NewWriter(&buf)
+		return writer.Write(header)
+	}
+	flush := func() {
+		if count == 0 {
+			return
+		}
+		writer.Flush()
+		im.sendBatch(count, func() (*meilisearch.TaskInfo, error) {
+			return im.index.AddDocumentsCsv(buf.Bytes(), &meilisearch.CsvDocumentsQuery{PrimaryKey: im.opts.PrimaryKey})
+		})
+		count = 0
+	}
+
+	if err := reset(); err != nil {
+		return err
+	}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("读取 CSV 失败: %w", err)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+		count++
+		if count >= im.opts.BatchSize {
+			flush()
+			if err := reset(); err != nil {
+				return err
+			}
+		}
+	}
+
+	flush()
+	return nil
+}
+
+// importJSON 流式解析 JSON 数组，逐个读取文档
+func (im *documentImporter) importJSON(r io.Reader) error {
+	decoder := json.NewDecoder(r)
+
+	token, err := decoder.Token()
+	if err != nil {
+		return fmt.Errorf("读取 JSON 失败: %w", err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("JSON 导入文件必须是文档数组")
+	}
+
+	batch := make([]json.RawMessage, 0, im.opts.BatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		documents := batch
+		im.sendBatch(len(documents), func() (*meilisearch.TaskInfo, error) {
+			return im.index.AddDocuments(documents, im.primaryKey()...)
+		})
+		batch = make([]json.RawMessage, 0, im.opts.BatchSize)
+	}
+
+	for position := 0; decoder.More(); position++ {
+		var doc json.RawMessage
+		if err := decoder.Decode(&doc); err != nil {
+			return fmt.Errorf("解析第 %d 个文档失败: %w", position+1, err)
+		}
+		if len(doc) == 0 || doc[0] != '{' {
+			return fmt.Errorf("第 %d 个元素不是 JSON 对象", position+1)
+		}
+		batch = append(batch, doc)
+		if len(batch) >= im.opts.BatchSize {
+			flush()
+		}
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return fmt.Errorf("JSON 数组未正确结束: %w", err)
+	}
+
+	flush()
+	return nil
+}
+
+// WaitImportBatches 等待所有批次的任务完成，记录每个批次的最终状态
+// 任务失败的批次计入 FailedBatches，超时返回 errTaskTimeout
+func (h *SearchHandler) WaitImportBatches(ctx context.Context, result *models.ImportResult) error {
+	for i := range result.Batches {
+		batch := &result.Batches[i]
+		if batch.Error != "" {
+			continue
+		}
+
+		state, err := h.WaitTask(ctx, batch.TaskUID)
+		if state != nil {
+			batch.Status = state.Status
+		}
+		if errors.Is(err, errTaskFailed) {
+			batch.Error = err.Error()
+			result.FailedBatches++
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	result.Success = result.FailedBatches == 0
+	return nil
+}
+
+// ImportDocumentsUpload 上传 NDJSON、CSV 或 JSON 数组文件批量导入文档
+// 支持直接发送文件内容或 multipart 表单中的 file 字段
+func (h *SearchHandler) ImportDocumentsUpload(c *gin.Context) {
+	st := h.state()
+	if err := st.checkTenantWrite(c); err != nil {
+		c.JSON(tenantErrorStatus(err), models.ImportResult{Error: err.Error()})
+		return
+	}
+
+	indexUID, err := st.resolveIndexUID(c)
+	if err != nil {
+		c.JSON(indexErrorStatus(err, http.StatusBadRequest), models.ImportResult{Error: err.Error()})
+		return
+	}
+
+	opts := ImportOptions{
+		Format:     c.Query("format"),
+		PrimaryKey: c.Query("primary_key"),
+	}
+	if size := c.Query("batch_size"); size != "" {
+		opts.BatchSize, err = strconv.Atoi(size)
+		if err != nil || opts.BatchSize <= 0 {
+			c.JSON(http.StatusBadRequest, models.ImportResult{Error: "batch_size 必须是正整数"})
+			return
+		}
+	}
+	if delimiter := c.Query("csv_delimiter"); delimiter != "" {
+		if utf8.RuneCountInString(delimiter) != 1 {
+			c.JSON(http.StatusBadRequest, models.ImportResult{Error: "csv_delimiter 必须是单个字符"})
+			return
+		}
+		opts.CSVDelimiter, _ = utf8.DecodeRuneInString(delimiter)
+	}
+
+	body := io.Reader(c.Request.Body)
+	contentType := c.ContentType()
+	if contentType == "multipart/form-data" {
+		file, filename, err := multipartFile(c.Request)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ImportResult{Error: err.Error()})
+			return
+		}
+		defer file.Close()
+		body = file
+		if opts.Format == "" {
+			opts.Format = DetectImportFormat("", filename)
+		}
+	}
+	if opts.Format == "" {
+		opts.Format = DetectImportFormat(contentType, "")
+	}
+
+	result, err := h.ImportDocuments(c.Request.Context(), indexUID, body, opts)
+	if result == nil {
+		c.JSON(http.StatusBadRequest, models.ImportResult{Error: err.Error()})
+		return
+	}
+	if timeout, _ := c.Value(taskWaitKey).(time.Duration); timeout > 0 && err == nil {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		if waitErr := h.WaitImportBatches(ctx, result); waitErr != nil {
+			result.Success = false
+			result.Error = waitErr.Error()
+			c.JSON(taskErrorStatus(waitErr), result)
+			return
+		}
+	}
+
+	status := http.StatusOK
+	switch {
+	case err != nil:
+		status = http.StatusBadRequest
+	case result.FailedBatches > 0:
+		status = http.StatusInternalServerError
+	}
+	c.JSON(status, result)
+}
+
+// multipartFile 流式读取 multipart 表单中的 file 字段，避免整个文件落盘或读入内存
+func multipartFile(req *http.Request) (io.ReadCloser, string, error) {
+	reader, err := req.MultipartReader()
+	if err != nil {
+		return nil, "", fmt.Errorf("解析上传表单失败: %w", err)
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, "", fmt.Errorf("上传表单中缺少 file 字段")
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("解析上传表单失败: %w", err)
+		}
+		if part.FormName() == "file" {
+			return part, part.FileName(), nil
+		}
+		part.Close()
+	}
+}
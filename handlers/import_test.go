@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"meili_dog/middleware"
+	"meili_dog/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDetectImportFormat(t *testing.T) {
+	tests := []struct {
+		contentType string
+		filename    string
+		want        string
+	}{
+		{contentType: "application/x-ndjson", want: ImportFormatNDJSON},
+		{contentType: "application/jsonl; charset=utf-8", want: ImportFormatNDJSON},
+		{contentType: "text/csv", want: ImportFormatCSV},
+		{contentType: "application/json", want: ImportFormatJSON},
+		{filename: "docs.JSONL", want: ImportFormatNDJSON},
+		{filename: "docs.csv", want: ImportFormatCSV},
+		{contentType: "application/octet-stream", filename: "docs.json", want: ImportFormatJSON},
+		{contentType: "text/plain", filename: "docs.txt", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := DetectImportFormat(tt.contentType, tt.filename); got != tt.want {
+			t.Errorf("DetectImportFormat(%q, %q) = %q，期望 %q", tt.contentType, tt.filename, got, tt.want)
+		}
+	}
+}
+
+// importMeili 按批次返回递增任务UID的 fakeMeili，包含 fail 的批次返回错误
+func importMeili(t *testing.T) *fakeMeili {
+	t.Helper()
+	meili := newFakeMeili(t)
+	var taskUID atomic.Int64
+	meili.handle("POST /indexes/users/documents", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		if bytes.Contains(body, []byte("fail")) {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"message":"invalid document","code":"invalid_document_fields","type":"invalid_request","link":""}`)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, `{"taskUid":%d,"indexUid":"users","status":"enqueued","type":"documentAdditionOrUpdate"}`, taskUID.Add(1))
+	})
+	return meili
+}
+
+func TestImportDocumentsUpload(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		contentType string
+		body        string
+		wantStatus  int
+		wantBatches []string // 每个批次发送给 Meilisearch 的内容
+		wantTotal   int
+		wantFailed  int
+	}{
+		{
+			name:        "NDJSON 分批",
+			query:       "?batch_size=2",
+			contentType: "application/x-ndjson",
+			body:        "{\"id\":1}\n\n{\"id\":2}\n{\"id\":3}",
+			wantStatus:  http.StatusOK,
+			wantBatches: []string{"{\"id\":1}\n{\"id\":2}\n", "{\"id\":3}\n"},
+			wantTotal:   3,
+		},
+		{
+			name:        "CSV 每批带表头",
+			query:       "?batch_size=1&format=csv&csv_delimiter=%3B",
+			contentType: "application/octet-stream",
+			body:        "id;name\n1;a\n2;b\n",
+			wantStatus:  http.StatusOK,
+			wantBatches: []string{"id,name\n1,a\n", "id,name\n2,b\n"},
+			wantTotal:   2,
+		},
+		{
+			name:        "JSON 数组",
+			query:       "?batch_size=2",
+			contentType: "application/json",
+			body:        `[{"id":1},{"id":2},{"id":3}]`,
+			wantStatus:  http.StatusOK,
+			wantBatches: []string{`[{"id":1},{"id":2}]`, `[{"id":3}]`},
+			wantTotal:   3,
+		},
+		{
+			name:        "格式错误时停止导入",
+			query:       "?batch_size=1",
+			contentType: "application/x-ndjson",
+			body:        "{\"id\":1}\n[1]\n{\"id\":3}\n",
+			wantStatus:  http.StatusBadRequest,
+			wantBatches: []string{"{\"id\":1}\n"},
+			wantTotal:   1,
+		},
+		{
+			name:        "批次失败后继续",
+			query:       "?batch_size=1",
+			contentType: "application/x-ndjson",
+			body:        "{\"id\":1,\"x\":\"fail\"}\n{\"id\":2}\n",
+			wantStatus:  http.StatusInternalServerError,
+			wantBatches: []string{"{\"id\":1,\"x\":\"fail\"}\n", "{\"id\":2}\n"},
+			wantTotal:   2,
+			wantFailed:  1,
+		},
+		{
+			name:        "无法推断格式",
+			contentType: "text/plain",
+			body:        "{\"id\":1}\n",
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "batch_size 不合法",
+			query:       "?batch_size=0",
+			contentType: "application/x-ndjson",
+			body:        "{\"id\":1}\n",
+			wantStatus:  http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meili := importMeili(t)
+			h := NewSearchHandler(testConfig(t, meili))
+			router := testRouter(t, func(r gin.IRoutes) { r.POST("/documents/import", h.ImportDocumentsUpload) })
+
+			req := httptest.NewRequest(http.MethodPost, "/documents/import"+tt.query, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			req.Header.Set(middleware.APIKeyHeader, "admin-key")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("状态码 = %d，期望 %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			checkImportBatches(t, meili, tt.wantBatches)
+			if tt.wantBatches == nil {
+				return
+			}
+
+			var result models.ImportResult
+			if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+				t.Fatal(err)
+			}
+			if result.TotalDocuments != tt.wantTotal || result.FailedBatches != tt.wantFailed || len(result.Batches) != len(tt.wantBatches) {
+				t.Errorf("结果 = %+v，期望 %d 个文档、%d 个批次失败", result, tt.wantTotal, tt.wantFailed)
+			}
+		})
+	}
+}
+
+// TestImportDocumentsMultipart multipart 表单按 file 字段的文件名推断格式
+func TestImportDocumentsMultipart(t *testing.T) {
+	meili := importMeili(t)
+	h := NewSearchHandler(testConfig(t, meili))
+	router := testRouter(t, func(r gin.IRoutes) { r.POST("/documents/import", h.ImportDocumentsUpload) })
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("note", "ignored")
+	file, _ := form.CreateFormFile("file", "docs.ndjson")
+	io.WriteString(file, "{\"id\":1}\n{\"id\":2}\n")
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/documents/import", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set(middleware.APIKeyHeader, "admin-key")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("状态码 = %d: %s", w.Code, w.Body.String())
+	}
+	checkImportBatches(t, meili, []string{"{\"id\":1}\n{\"id\":2}\n"})
+}
+
+// checkImportBatches 检查 Meilisearch 收到的批次内容
+func checkImportBatches(t *testing.T, meili *fakeMeili, want []string) {
+	t.Helper()
+	requests := meili.received("POST /indexes/users/documents")
+	if len(requests) != len(want) {
+		t.Fatalf("收到 %d 个批次，期望 %d 个", len(requests), len(want))
+	}
+	for i, req := range requests {
+		if req.Body != want[i] {
+			t.Errorf("第 %d 个批次 = %q，期望 %q", i+1, req.Body, want[i])
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"meili_dog/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errIndexNotConfigured = errors.New("索引未配置")
+	errIndexNotAllowed    = errors.New("索引不在允许访问的列表中")
+)
+
+// buildIndexAllowList 根据配置生成默认索引和允许访问的索引列表
+// search.index_uid 优先作为默认索引，未设置时使用 [[indexes]] 中的第一个
+func buildIndexAllowList(config models.AppConfig) (string, map[string]models.SearchOptimization) {
+	indexes := make(map[string]models.SearchOptimization)
+	defaultUID := config.Search.IndexUID
+
+	if defaultUID != "" {
+		indexes[defaultUID] = config.Search.Optimization
+	}
+
+	for _, idx := range config.Indexes {
+		if idx.UID == "" {
+			continue
+		}
+		opt := config.Search.Optimization
+		if idx.Optimization != nil {
+			opt = *idx.Optimization
+		}
+		indexes[idx.UID] = opt
+		if defaultUID == "" {
+			defaultUID = idx.UID
+		}
+	}
+
+	return defaultUID, indexes
+}
+
+// resolveIndexUID 解析请求的目标索引
+// 路由中带 :uid 时必须在允许列表中，否则使用默认索引
+func (st *handlerState) resolveIndexUID(c *gin.Context) (string, error) {
+	uid := c.Param("uid")
+	if uid == "" {
+		if st.indexUID == "" {
+			return "", errIndexNotConfigured
+		}
+		return st.indexUID, nil
+	}
+
+	if err := st.checkIndexAllowed(uid); err != nil {
+		return "", err
+	}
+	return uid, nil
+}
+
+// checkIndexAllowed 检查索引是否在允许列表中
+func (st *handlerState) checkIndexAllowed(uid string) error {
+	if _, ok := st.indexes[uid]; !ok {
+		return fmt.Errorf("%w: %s", errIndexNotAllowed, uid)
+	}
+	return nil
+}
+
+// indexErrorStatus 根据索引解析错误返回HTTP状态码
+func indexErrorStatus(err error, fallback int) int {
+	if errors.Is(err, errIndexNotAllowed) {
+		return http.StatusNotFound
+	}
+	return fallback
+}
+
+// ListIndexes 列出允许访问的索引
+func (h *SearchHandler) ListIndexes(c *gin.Context) {
+	st := h.state()
+	uids := make([]string, 0, len(st.indexes))
+	for uid := range st.indexes {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+
+	c.JSON(http.StatusOK, gin.H{
+		"default": st.indexUID,
+		"indexes": uids,
+	})
+}
+
+// DefaultIndexUID 返回默认索引UID
+func (h *SearchHandler) DefaultIndexUID() string {
+	return h.state().indexUID
+}
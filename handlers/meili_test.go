@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"meili_dog/middleware"
+	"meili_dog/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// fakeMeili 测试用的 Meilisearch，按 "METHOD /path" 注册响应并记录收到的请求
+type fakeMeili struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	routes   map[string]http.HandlerFunc
+	requests []fakeRequest
+}
+
+// fakeRequest fakeMeili 收到的请求
+type fakeRequest struct {
+	Route string
+	Query string
+	Body  string
+}
+
+func newFakeMeili(t *testing.T) *fakeMeili {
+	t.Helper()
+	m := &fakeMeili{routes: make(map[string]http.HandlerFunc)}
+	m.Server = httptest.NewServer(http.HandlerFunc(m.serve))
+	t.Cleanup(m.Close)
+	return m
+}
+
+func (m *fakeMeili) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(strings.NewReader(string(body)))
+	route := r.Method + " " + r.URL.Path
+
+	m.mu.Lock()
+	m.requests = append(m.requests, fakeRequest{Route: route, Query: r.URL.RawQuery, Body: string(body)})
+	handler, ok := m.routes[route]
+	m.mu.Unlock()
+
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"message":"not found: `+route+`","code":"not_found","type":"invalid_request","link":""}`)
+		return
+	}
+	handler(w, r)
+}
+
+// handle 注册路由的处理函数
+func (m *fakeMeili) handle(route string, handler http.HandlerFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.routes[route] = handler
+}
+
+// reply 注册路由的固定 JSON 响应
+func (m *fakeMeili) reply(route string, status int, body string) {
+	m.handle(route, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	})
+}
+
+// received 返回路由收到的请求
+func (m *fakeMeili) received(route string) []fakeRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var result []fakeRequest
+	for _, req := range m.requests {
+		if req.Route == route {
+			result = append(result, req)
+		}
+	}
+	return result
+}
+
+// testConfig 指向 fakeMeili 的最小配置
+func testConfig(t *testing.T, m *fakeMeili) models.AppConfig {
+	t.Helper()
+	var cfg models.AppConfig
+	cfg.Server.Address = m.URL
+	cfg.Search.IndexUID = "users"
+	cfg.Snapshots.Dir = t.TempDir()
+	return cfg
+}
+
+// testKeys 测试用的 API 密钥，tenant-key 和 tenant-admin-key 属于租户 a
+var testKeys = []models.APIKeyConfig{
+	{Name: "frontend", Key: "search-key", Scopes: []string{models.ScopeSearch}},
+	{Name: "ops", Key: "admin-key", Scopes: []string{models.ScopeAdmin}},
+	{Name: "shop-a", Key: "tenant-key", Scopes: []string{models.ScopeSearch}, Tenant: "a"},
+	{Name: "shop-a-ops", Key: "tenant-admin-key", Scopes: []string{models.ScopeAdmin}, Tenant: "a"},
+	{Name: "shop-quote", Key: "quote-key", Scopes: []string{models.ScopeSearch}, Tenant: `a"x`},
+}
+
+// testRouter 创建经过 API 密钥认证的路由，register 注册被测接口
+func testRouter(t *testing.T, register func(r gin.IRoutes)) *gin.Engine {
+	t.Helper()
+	auth, err := middleware.NewAuth(models.AuthConfig{Providers: []string{"api_key"}, APIKeys: testKeys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := gin.New()
+	register(router.Group("", auth.Authenticate()))
+	return router
+}
+
+// do 发送请求，key 为空时不带 API 密钥
+func do(router http.Handler, method, target, key, body string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if key != "" {
+		req.Header.Set(middleware.APIKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
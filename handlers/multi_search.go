@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"meili_dog/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/meilisearch/meilisearch-go"
+)
+
+// maxMultiSearchQueries 单次多索引搜索允许的最大查询数
+const maxMultiSearchQueries = 20
+
+// federatedHit 联合搜索中带权重得分的命中结果
+type federatedHit struct {
+	hit   map[string]interface{}
+	score float64
+}
+
+// MultiSearch 一次请求搜索多个索引
+// 默认分别返回每个查询的结果，设置 federation 时按加权得分合并为一个列表
+func (h *SearchHandler) MultiSearch(c *gin.Context) {
+	st := h.state()
+
+	var req models.MultiSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(req.Queries) > maxMultiSearchQueries {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("查询数量不能超过 %d", maxMultiSearchQueries)})
+		return
+	}
+
+	federation := req.Federation
+	if federation != nil {
+		if err := applyFederationPagination(federation, st.defaults); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	tenantFilter, err := st.tenantFilter(c)
+	if err != nil {
+		c.JSON(tenantErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	queries := make([]meilisearch.SearchRequest, 0, len(req.Queries))
+	for i := range req.Queries {
+		query := &req.Queries[i]
+		if err := st.checkIndexAllowed(query.IndexUID); err != nil {
+			c.JSON(indexErrorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+			return
+		}
+		if query.Cursor != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("queries[%d]: 多索引搜索不支持 cursor", i)})
+			return
+		}
+		if query.Weight < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "weight 不能为负数"})
+			return
+		}
+		if query.Weight == 0 {
+			query.Weight = 1
+		}
+
+		searchRequest, err := h.buildSearchRequest(c.Request.Context(), st, query.IndexUID, &query.SearchRequest, tenantFilter)
+		if err != nil {
+			c.JSON(filterErrorStatus(err), gin.H{"error": fmt.Sprintf("queries[%d]: %s", i, err.Error())})
+			return
+		}
+		searchRequest.IndexUID = query.IndexUID
+
+		// 联合搜索需要每个索引的前 N 条结果和得分，再统一分页
+		if federation != nil {
+			searchRequest.Offset = 0
+			searchRequest.Limit = int64(federation.Page * federation.Limit)
+			searchRequest.ShowRankingScore = true
+		}
+
+		queries = append(queries, *searchRequest)
+	}
+
+	call := startUpstream(c.Request.Context(), "multi_search")
+	result, err := st.client.MultiSearch(&meilisearch.MultiSearchRequest{Queries: queries})
+	call.end(err)
+	if err != nil {
+		logError(c, "多索引搜索错误", err, "queries", len(queries))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "搜索失败: " + err.Error()})
+		return
+	}
+	for i := range result.Results {
+		observeSearch(req.Queries[i].IndexUID, &result.Results[i], false)
+	}
+
+	if federation != nil {
+		c.JSON(http.StatusOK, h.mergeFederatedResults(req.Queries, result.Results, federation))
+		return
+	}
+
+	response := models.MultiSearchResponse{Results: make([]models.SearchResponse, 0, len(result.Results))}
+	for i := range result.Results {
+		query := &req.Queries[i]
+		response.Results = append(response.Results, h.buildSearchResponse(c.Request.Context(), query.IndexUID, &query.SearchRequest, &result.Results[i]))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// mergeFederatedResults 按 _rankingScore * weight 合并多个查询的结果并分页
+func (h *SearchHandler) mergeFederatedResults(queries []models.MultiSearchQuery, results []meilisearch.SearchResponse, federation *models.FederationOptions) models.SearchResponse {
+	var (
+		merged           []federatedHit
+		totalHits        int64
+		processingTimeMs int64
+	)
+
+	for i := range results {
+		query := &queries[i]
+		totalHits += results[i].EstimatedTotalHits
+		processingTimeMs += results[i].ProcessingTimeMs
+
+		for position, hit := range h.convertHits(results[i].Hits) {
+			score, _ := hit["_rankingScore"].(float64)
+			weighted := score * query.Weight
+			hit["_federation"] = gin.H{
+				"indexUid":             query.IndexUID,
+				"queriesPosition":      i,
+				"hitPosition":          position,
+				"weightedRankingScore": weighted,
+			}
+			merged = append(merged, federatedHit{hit: hit, score: weighted})
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].score > merged[j].score
+	})
+
+	offset := (federation.Page - 1) * federation.Limit
+	hits := make([]map[string]interface{}, 0, federation.Limit)
+	for i := offset; i < len(merged) && i < offset+federation.Limit; i++ {
+		hits = append(hits, merged[i].hit)
+	}
+
+	response := models.SearchResponse{
+		Hits:               hits,
+		EstimatedTotalHits: totalHits,
+		Limit:              federation.Limit,
+		Offset:             offset,
+		Page:               federation.Page,
+		ProcessingTimeMs:   int(processingTimeMs),
+	}
+	if totalHits > 0 {
+		response.TotalPages = int((totalHits + int64(federation.Limit) - 1) / int64(federation.Limit))
+	}
+
+	return response
+}
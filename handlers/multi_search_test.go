@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"meili_dog/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multiSearchResults 两个索引各两条带得分的结果
+const multiSearchResults = `{"results":[
+	{"indexUid":"users","hits":[{"id":1,"_rankingScore":0.9},{"id":2,"_rankingScore":0.5}],"estimatedTotalHits":2,"processingTimeMs":1},
+	{"indexUid":"orders","hits":[{"id":10,"_rankingScore":0.6},{"id":11,"_rankingScore":0.2}],"estimatedTotalHits":2,"processingTimeMs":2}
+]}`
+
+func TestMultiSearch(t *testing.T) {
+	meili := newFakeMeili(t)
+	meili.reply("POST /multi-search", http.StatusOK, multiSearchResults)
+
+	cfg := testConfig(t, meili)
+	cfg.Indexes = []models.IndexConfig{{UID: "orders"}}
+	h := NewSearchHandler(cfg)
+	router := testRouter(t, func(r gin.IRoutes) { r.POST("/multi-search", h.MultiSearch) })
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantIDs    []float64 // 联合搜索合并后的文档ID顺序
+		wantPage   int
+	}{
+		{
+			name:       "分别返回",
+			body:       `{"queries":[{"index_uid":"users","query":"a"},{"index_uid":"orders","query":"a"}]}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "按得分合并",
+			body:       `{"queries":[{"index_uid":"users","query":"a"},{"index_uid":"orders","query":"a"}],"federation":{"limit":10}}`,
+			wantStatus: http.StatusOK,
+			wantIDs:    []float64{1, 10, 2, 11},
+			wantPage:   1,
+		},
+		{
+			name:       "权重改变顺序",
+			body:       `{"queries":[{"index_uid":"users","query":"a"},{"index_uid":"orders","query":"a","weight":2}],"federation":{"limit":10}}`,
+			wantStatus: http.StatusOK,
+			wantIDs:    []float64{10, 1, 2, 11},
+			wantPage:   1,
+		},
+		{
+			name:       "合并后分页",
+			body:       `{"queries":[{"index_uid":"users","query":"a"},{"index_uid":"orders","query":"a"}],"federation":{"page":2,"limit":3}}`,
+			wantStatus: http.StatusOK,
+			wantIDs:    []float64{11},
+			wantPage:   2,
+		},
+		{
+			name:       "索引不在允许列表中",
+			body:       `{"queries":[{"index_uid":"secrets","query":"a"}]}`,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "权重为负数",
+			body:       `{"queries":[{"index_uid":"users","query":"a","weight":-1}]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "不支持游标",
+			body:       `{"queries":[{"index_uid":"users","query":"a","cursor":"abc"}]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "查询数量过多",
+			body:       `{"queries":[` + strings.Repeat(`{"index_uid":"users","query":"a"},`, maxMultiSearchQueries) + `{"index_uid":"users","query":"a"}]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := do(router, http.MethodPost, "/multi-search", "search-key", tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("状态码 = %d，期望 %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			if tt.wantIDs == nil {
+				var resp models.MultiSearchResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+					t.Fatal(err)
+				}
+				if len(resp.Results) != 2 || len(resp.Results[0].Hits) != 2 || len(resp.Results[1].Hits) != 2 {
+					t.Fatalf("结果 = %s，期望两个查询各两条", w.Body.String())
+				}
+				return
+			}
+
+			var resp models.SearchResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			ids := make([]float64, 0, len(resp.Hits))
+			for _, hit := range resp.Hits {
+				id, _ := hit["id"].(float64)
+				ids = append(ids, id)
+			}
+			if !slices.Equal(ids, tt.wantIDs) {
+				t.Errorf("文档顺序 = %v，期望 %v", ids, tt.wantIDs)
+			}
+			if resp.Page != tt.wantPage || resp.EstimatedTotalHits != 4 || resp.TotalPages != (4+resp.Limit-1)/resp.Limit {
+				t.Errorf("分页 = page %d, total %d, pages %d", resp.Page, resp.EstimatedTotalHits, resp.TotalPages)
+			}
+		})
+	}
+}
+
+// TestMultiSearchFederationRequest 联合搜索向每个索引请求前 page*limit 条结果和得分
+func TestMultiSearchFederationRequest(t *testing.T) {
+	meili := newFakeMeili(t)
+	meili.reply("POST /multi-search", http.StatusOK, multiSearchResults)
+
+	cfg := testConfig(t, meili)
+	cfg.Indexes = []models.IndexConfig{{UID: "orders"}}
+	h := NewSearchHandler(cfg)
+	router := testRouter(t, func(r gin.IRoutes) { r.POST("/multi-search", h.MultiSearch) })
+
+	body := `{"queries":[{"index_uid":"users","query":"a","offset":5},{"index_uid":"orders","query":"a"}],"federation":{"page":2,"limit":3}}`
+	if w := do(router, http.MethodPost, "/multi-search", "search-key", body); w.Code != http.StatusOK {
+		t.Fatalf("状态码 = %d: %s", w.Code, w.Body.String())
+	}
+
+	requests := meili.received("POST /multi-search")
+	if len(requests) != 1 {
+		t.Fatalf("收到 %d 次请求，期望 1 次", len(requests))
+	}
+	var sent struct {
+		Queries []struct {
+			IndexUID         string `json:"indexUid"`
+			Offset           int    `json:"offset"`
+			Limit            int    `json:"limit"`
+			ShowRankingScore bool   `json:"showRankingScore"`
+		} `json:"queries"`
+	}
+	if err := json.Unmarshal([]byte(requests[0].Body), &sent); err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range sent.Queries {
+		if q.Offset != 0 || q.Limit != 6 || !q.ShowRankingScore {
+			t.Errorf("%s: offset %d, limit %d, showRankingScore %v，期望 0, 6, true", q.IndexUID, q.Offset, q.Limit, q.ShowRankingScore)
+		}
+	}
+}
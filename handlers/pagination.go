@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"meili_dog/models"
+)
+
+// errInvalidSearchParams 分页参数或搜索词超出限制
+var errInvalidSearchParams = errors.New("搜索参数无效")
+
+// searchDefaults 填充 search.default 中未设置的参数
+func searchDefaults(d models.SearchDefault) models.SearchDefault {
+	if d.Limit <= 0 {
+		d.Limit = models.DefaultSearchLimit
+	}
+	if d.MaxLimit <= 0 {
+		d.MaxLimit = models.DefaultMaxLimit
+	}
+	if d.MaxPageDepth <= 0 {
+		d.MaxPageDepth = models.DefaultMaxPageDepth
+	}
+	if d.MaxQueryLength <= 0 {
+		d.MaxQueryLength = models.DefaultMaxQueryLength
+	}
+	return d
+}
+
+// applyPagination 根据 page 或 offset 计算偏移量，并检查每页数量和翻页深度
+// 请求中 page 和 offset 都未设置时使用 search.default.offset
+func applyPagination(req *models.SearchRequest, d models.SearchDefault) error {
+	if err := checkQueryLength(req.Query, d); err != nil {
+		return err
+	}
+
+	limit, err := checkLimit("limit", req.Limit, d)
+	if err != nil {
+		return err
+	}
+	req.Limit = limit
+
+	// 游标分页不使用偏移量，不受翻页深度限制
+	if req.Cursor != "" {
+		if req.Page != 0 || req.RequestedOffset != nil {
+			return fmt.Errorf("%w: cursor 不能与 page、offset 同时使用", errInvalidSearchParams)
+		}
+		req.Page, req.Offset = 0, 0
+		return nil
+	}
+
+	switch {
+	case req.Page < 0:
+		return fmt.Errorf("%w: page 不能为负数", errInvalidSearchParams)
+	case req.RequestedOffset != nil && *req.RequestedOffset < 0:
+		return fmt.Errorf("%w: offset 不能为负数", errInvalidSearchParams)
+	case req.Page > 0 && req.RequestedOffset != nil:
+		return fmt.Errorf("%w: page 和 offset 不能同时使用", errInvalidSearchParams)
+	case req.Page > 0:
+		req.Offset = (req.Page - 1) * req.Limit
+	case req.RequestedOffset != nil:
+		req.Offset = *req.RequestedOffset
+	default:
+		req.Offset = d.Offset
+	}
+	// 按偏移量计算所在页，offset 不是 limit 的整数倍时为包含第一条结果的页
+	req.Page = req.Offset/req.Limit + 1
+
+	return checkPageDepth(req.Offset, req.Limit, d)
+}
+
+// applyFederationPagination 设置联合搜索的默认分页参数并检查限制
+func applyFederationPagination(federation *models.FederationOptions, d models.SearchDefault) error {
+	limit, err := checkLimit("federation.limit", federation.Limit, d)
+	if err != nil {
+		return err
+	}
+	federation.Limit = limit
+	if federation.Page < 1 {
+		federation.Page = 1
+	}
+	return checkPageDepth((federation.Page-1)*federation.Limit, federation.Limit, d)
+}
+
+// checkLimit 检查每页数量，未设置时使用默认值
+func checkLimit(name string, limit int, d models.SearchDefault) (int, error) {
+	if limit < 0 {
+		return 0, fmt.Errorf("%w: %s 不能为负数", errInvalidSearchParams, name)
+	}
+	if limit == 0 {
+		limit = d.Limit
+	}
+	if limit > d.MaxLimit {
+		return 0, fmt.Errorf("%w: %s 不能超过 %d", errInvalidSearchParams, name, d.MaxLimit)
+	}
+	return limit, nil
+}
+
+// checkPageDepth Meilisearch 最多返回 maxTotalHits 条结果，翻页越深越慢
+func checkPageDepth(offset, limit int, d models.SearchDefault) error {
+	if offset+limit > d.MaxPageDepth {
+		return fmt.Errorf("%w: 翻页过深，offset + limit 不能超过 %d，请缩小搜索范围", errInvalidSearchParams, d.MaxPageDepth)
+	}
+	return nil
+}
+
+// checkQueryLength 检查搜索词的字符数
+func checkQueryLength(query string, d models.SearchDefault) error {
+	if utf8.RuneCountInString(query) > d.MaxQueryLength {
+		return fmt.Errorf("%w: 搜索词不能超过 %d 个字符", errInvalidSearchParams, d.MaxQueryLength)
+	}
+	return nil
+}
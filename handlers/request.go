@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"meili_dog/metrics"
+	"meili_dog/tracing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/meilisearch/meilisearch-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// meiliRequestTimeout 直接请求 Meilisearch 的超时时间
+const meiliRequestTimeout = 30 * time.Second
+
+// meiliAPIError Meilisearch 返回的错误信息
+type meiliAPIError struct {
+	StatusCode int    `json:"-"`
+	Message    string `json:"message"`
+	Code       string `json:"code"`
+	Type       string `json:"type"`
+	Link       string `json:"link"`
+}
+
+func (e *meiliAPIError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("Meilisearch 返回状态码 %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("Meilisearch 返回状态码 %d: %s (code: %s, type: %s)", e.StatusCode, e.Message, e.Code, e.Type)
+}
+
+// doRequest 直接调用 Meilisearch HTTP API，用于 SDK 尚未支持的接口
+func (h *SearchHandler) doRequest(ctx context.Context, st *handlerState, method, path string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("序列化请求失败: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	call := startUpstream(ctx, upstreamOperation(method, path))
+	server := st.config.Server
+	url := strings.TrimRight(server.Address, "/") + path
+	req, err := http.NewRequestWithContext(call.ctx, method, url, reader)
+	if err != nil {
+		call.end(err)
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if server.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+server.APIKey)
+	}
+	otel.GetTextMapPropagator().Inject(call.ctx, propagation.HeaderCarrier(req.Header))
+
+	err = h.sendRequest(req, result)
+	call.end(err)
+	return err
+}
+
+// sendRequest 发送请求并解析响应，4xx 和 5xx 响应转换为 meiliAPIError
+func (h *SearchHandler) sendRequest(req *http.Request, result interface{}) error {
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求 Meilisearch 失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取 Meilisearch 响应失败: %w", err)
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := &meiliAPIError{StatusCode: resp.StatusCode}
+		if err := json.Unmarshal(data, apiErr); err != nil || apiErr.Message == "" {
+			apiErr.Message = string(data)
+		}
+		return apiErr
+	}
+
+	if result == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("解析 Meilisearch 响应失败: %w", err)
+	}
+	return nil
+}
+
+// upstreamOperation 生成 Meilisearch 请求的指标标签，索引名替换为 :uid，查询参数不计入
+func upstreamOperation(method, path string) string {
+	path, _, _ = strings.Cut(path, "?")
+	segments := strings.Split(path, "/")
+	for i := 1; i < len(segments); i++ {
+		if segments[i-1] == "indexes" {
+			segments[i] = ":uid"
+		}
+	}
+	return method + " " + strings.Join(segments, "/")
+}
+
+// upstreamCall 一次 Meilisearch 请求，结束时记录耗时指标和客户端 span
+type upstreamCall struct {
+	ctx       context.Context
+	operation string
+	start     time.Time
+	span      trace.Span
+}
+
+// startUpstream 开始一次 Meilisearch 请求，调用方在请求返回后调用 end
+func startUpstream(ctx context.Context, operation string) *upstreamCall {
+	ctx, span := tracing.Start(ctx, "meilisearch "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemKey.String("meilisearch"), semconv.DBOperationName(operation)),
+	)
+	return &upstreamCall{ctx: ctx, operation: operation, start: time.Now(), span: span}
+}
+
+// end 记录请求的耗时和错误，Meilisearch 返回的错误码记录在 span 上
+func (u *upstreamCall) end(err error) {
+	metrics.ObserveUpstream(u.operation, time.Since(u.start), err)
+	if status, code := upstreamErrorCode(err); status != 0 {
+		u.span.SetAttributes(semconv.HTTPResponseStatusCode(status), attribute.String("meilisearch.error.code", code))
+	}
+	tracing.End(u.span, err)
+}
+
+// upstreamErrorStatus Meilisearch 返回 4xx 时透传状态码，其他错误返回 500
+func upstreamErrorStatus(err error) int {
+	var apiErr *meiliAPIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode >= 400 && apiErr.StatusCode < 500 {
+		return apiErr.StatusCode
+	}
+	return http.StatusInternalServerError
+}
+
+// logError 记录处理请求时的错误，日志带上请求 ID 和 Meilisearch 的错误码
+func logError(c *gin.Context, msg string, err error, args ...any) {
+	slog.ErrorContext(c.Request.Context(), msg, append(args, errorAttrs(err)...)...)
+}
+
+// errorAttrs 把错误转换为日志字段，Meilisearch 错误额外记录状态码、code 和 type
+func errorAttrs(err error) []any {
+	attrs := []any{"error", err.Error()}
+
+	var apiErr *meiliAPIError
+	if errors.As(err, &apiErr) {
+		return append(attrs, "status", apiErr.StatusCode, "code", apiErr.Code, "type", apiErr.Type)
+	}
+	var sdkErr *meilisearch.Error
+	if errors.As(err, &sdkErr) {
+		attrs = append(attrs, "status", sdkErr.StatusCode)
+		if sdkErr.MeilisearchApiError.Code != "" {
+			attrs = append(attrs, "code", sdkErr.MeilisearchApiError.Code, "type", sdkErr.MeilisearchApiError.Type)
+		}
+	}
+	return attrs
+}
+
+// upstreamErrorCode 返回 Meilisearch 错误的状态码和错误码，不是 Meilisearch 返回的错误时状态码为 0
+func upstreamErrorCode(err error) (int, string) {
+	var apiErr *meiliAPIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode, apiErr.Code
+	}
+	var sdkErr *meilisearch.Error
+	if errors.As(err, &sdkErr) && sdkErr.StatusCode != 0 {
+		return sdkErr.StatusCode, sdkErr.MeilisearchApiError.Code
+	}
+	return 0, ""
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+
+	"meili_dog/models"
+	"meili_dog/schema"
+
+	"github.com/gin-gonic/gin"
+)
+
+// buildFieldSchemas 生成各索引的字段类型，[[indexes]] 未配置 fields 时沿用 [fields]
+// 字段类型在加载配置时已校验，这里出错的索引视为未配置字段类型
+func buildFieldSchemas(config models.AppConfig) map[string]*schema.Schema {
+	schemas := make(map[string]*schema.Schema)
+	add := func(uid string, fields *models.FieldConfig) {
+		if s, err := schema.New(fields); err == nil && s != nil && uid != "" {
+			schemas[uid] = s
+		}
+	}
+
+	add(config.Search.IndexUID, &config.Fields)
+	for i := range config.Indexes {
+		idx := &config.Indexes[i]
+		fields := &config.Fields
+		if idx.Fields != nil {
+			fields = idx.Fields
+		}
+		add(idx.UID, fields)
+	}
+	return schemas
+}
+
+// GetSchema 返回索引文档的 JSON Schema
+func (h *SearchHandler) GetSchema(c *gin.Context) {
+	st := h.state()
+	indexUID, err := st.resolveIndexUID(c)
+	if err != nil {
+		c.JSON(indexErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	s := st.schemas[indexUID]
+	if s == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "索引未配置字段类型 ([fields] 或 [indexes.fields])"})
+		return
+	}
+	c.JSON(http.StatusOK, s.JSONSchema(indexUID))
+}
@@ -1,38 +1,85 @@
 package handlers
 
 import (
-	"log"
+	"context"
+	"errors"
 	"net/http"
 	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"meili_dog/metrics"
 	"meili_dog/models"
+	"meili_dog/schema"
+	"meili_dog/tracing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/meilisearch/meilisearch-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // SearchHandler 搜索处理器
 type SearchHandler struct {
+	current     atomic.Pointer[handlerState] // 当前配置，热加载时整体替换
+	startup     models.AppConfig             // 启动时的配置，用于判断哪些修改需要重启
+	filterable  *attributeCache              // 可过滤字段缓存
+	sortable    *attributeCache              // 可排序字段缓存，游标分页校验排序字段
+	httpClient  *http.Client                 // 直接请求 Meilisearch 的客户端
+	snapshots   *snapshotStore               // 设置快照
+	cache       *searchCache                 // 搜索结果缓存，未启用时为 nil
+	primaryKeys sync.Map                     // 索引主键，游标分页用作最后的排序字段
+}
+
+// handlerState 可热加载的配置和依赖配置的对象，创建后不再修改
+type handlerState struct {
 	client   *meilisearch.Client
 	config   models.AppConfig
-	indexUID string
+	indexUID string                               // 默认索引
+	indexes  map[string]models.SearchOptimization // 允许访问的索引及其优化参数
+	schemas  map[string]*schema.Schema            // 配置了字段类型的索引
+	defaults models.SearchDefault                 // 默认搜索参数和限制，未设置的已填充默认值
+	loadedAt time.Time
 }
 
 // NewSearchHandler 创建新的搜索处理器
 func NewSearchHandler(config models.AppConfig) *SearchHandler {
+	h := &SearchHandler{
+		startup:    config,
+		filterable: newAttributeCache(),
+		sortable:   newAttributeCache(),
+		httpClient: &http.Client{Timeout: meiliRequestTimeout},
+		snapshots:  newSnapshotStore(config.Snapshots.Dir, config.Snapshots.MaxPerIndex),
+		cache:      newSearchCache(config.Cache.Enabled, config.Cache.MaxEntries, config.Cache.TTL),
+	}
+	h.current.Store(newHandlerState(config))
+	return h
+}
+
+func newHandlerState(config models.AppConfig) *handlerState {
 	client := meilisearch.NewClient(meilisearch.ClientConfig{
 		Host:   config.Server.Address,
 		APIKey: config.Server.APIKey,
 	})
-
-	return &SearchHandler{
+	indexUID, indexes := buildIndexAllowList(config)
+	return &handlerState{
 		client:   client,
 		config:   config,
-		indexUID: config.Search.IndexUID,
+		indexUID: indexUID,
+		indexes:  indexes,
+		schemas:  buildFieldSchemas(config),
+		defaults: searchDefaults(config.Search.Default),
+		loadedAt: time.Now(),
 	}
 }
 
-// Search 执行搜索
+// state 返回当前配置，处理器开始时调用一次并把结果传给辅助函数，避免同一请求读到热加载前后不一致的配置
+func (h *SearchHandler) state() *handlerState {
+	return h.current.Load()
+}
+
+// Search 执行搜索（GET，查询参数）
 func (h *SearchHandler) Search(c *gin.Context) {
 	var req models.SearchRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
@@ -40,45 +87,139 @@ func (h *SearchHandler) Search(c *gin.Context) {
 		return
 	}
 
-	// 检查索引UID是否配置
-	if h.indexUID == "" {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "索引未配置"})
+	if len(req.Filters) == 0 {
+		req.Filters = queryFilters(c)
+	}
+
+	filterExpr, err := parseFilterParam(req.Filter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	req.FilterExpr = filterExpr
 
-	// 计算偏移量
-	if req.Page < 1 {
-		req.Page = 1
+	h.search(c, &req)
+}
+
+// queryFilters 读取 filters[field]=value 形式的简单过滤条件
+func queryFilters(c *gin.Context) map[string]interface{} {
+	values := c.QueryMap("filters")
+	if len(values) == 0 {
+		return nil
 	}
-	req.Offset = (req.Page - 1) * req.Limit
 
-	// 构建搜索参数
-	searchRequest := &meilisearch.SearchRequest{
-		Query:  req.Query,
-		Limit:  int64(req.Limit),
-		Offset: int64(req.Offset),
+	filters := make(map[string]interface{}, len(values))
+	for field, value := range values {
+		filters[field] = value
 	}
+	return filters
+}
 
-	// 应用优化参数
-	h.applyOptimizationParams(searchRequest)
+// SearchPost 执行搜索（POST，JSON 请求体），适合嵌套过滤和数组参数
+func (h *SearchHandler) SearchPost(c *gin.Context) {
+	var req models.SearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	// 应用过滤条件
-	h.applyFilters(searchRequest, req.Filters)
+	h.search(c, &req)
+}
 
-	// 应用排序
-	if len(req.Sort) > 0 {
-		searchRequest.Sort = req.Sort
+// search GET 和 POST 搜索共用的执行流程
+func (h *SearchHandler) search(c *gin.Context, req *models.SearchRequest) {
+	st := h.state()
+
+	// 解析目标索引
+	indexUID, err := st.resolveIndexUID(c)
+	if err != nil {
+		c.JSON(indexErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	tenantFilter, err := st.tenantFilter(c)
+	if err != nil {
+		c.JSON(tenantErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	// 游标分页
+	var cursor *searchCursor
+	if req.Cursor != "" {
+		if cursor, err = h.prepareCursor(c.Request.Context(), st, indexUID, req); err != nil {
+			c.JSON(filterErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	searchRequest, err := h.buildSearchRequest(c.Request.Context(), st, indexUID, req, tenantFilter)
+	if err == nil && cursor != nil {
+		err = h.applyCursor(c.Request.Context(), st, cursor, searchRequest)
+	}
+	if err != nil {
+		c.JSON(filterErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	respond := func(result *meilisearch.SearchResponse) {
+		response := h.buildSearchResponse(c.Request.Context(), indexUID, req, result)
+		if cursor != nil {
+			next, err := cursor.next(response.Hits, req.Limit)
+			if err != nil {
+				status := http.StatusUnprocessableEntity
+				if errors.Is(err, errInvalidSearchParams) {
+					status = http.StatusBadRequest
+				}
+				c.JSON(status, gin.H{"error": err.Error()})
+				return
+			}
+			response.NextCursor = next
+		}
+		c.JSON(http.StatusOK, response)
+	}
+
+	// 优先使用缓存的结果
+	var (
+		cacheKey        string
+		cacheGeneration = h.cache.generation(indexUID)
+	)
+	if h.cache != nil {
+		cacheKey = searchCacheKey(indexUID, searchRequest)
+		if result, ok := h.cache.get(cacheKey); ok {
+			c.Header(searchCacheHeader, "HIT")
+			observeSearch(indexUID, result, true)
+			respond(result)
+			return
+		}
+		c.Header(searchCacheHeader, "MISS")
 	}
 
 	// 执行搜索
-	result, err := h.client.Index(h.indexUID).Search(req.Query, searchRequest)
+	call := startUpstream(c.Request.Context(), "search")
+	result, err := st.client.Index(indexUID).Search(req.Query, searchRequest)
+	call.end(err)
 	if err != nil {
-		log.Printf("搜索错误: %v", err)
+		logError(c, "搜索错误", err, "index", indexUID)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "搜索失败: " + err.Error()})
 		return
 	}
+	h.cache.set(cacheKey, indexUID, cacheGeneration, result)
+	observeSearch(indexUID, result, false)
+
+	respond(result)
+}
+
+// observeSearch 记录搜索结果的处理时间和是否为零结果
+func observeSearch(indexUID string, result *meilisearch.SearchResponse, cached bool) {
+	totalHits := max(result.EstimatedTotalHits, result.TotalHits, int64(len(result.Hits)))
+	metrics.ObserveSearch(indexUID, result.ProcessingTimeMs, totalHits, cached)
+}
+
+// buildSearchResponse 将 Meilisearch 搜索结果转换为响应
+func (h *SearchHandler) buildSearchResponse(ctx context.Context, indexUID string, req *models.SearchRequest, result *meilisearch.SearchResponse) models.SearchResponse {
+	_, span := tracing.Start(ctx, "build_search_response", trace.WithAttributes(attribute.Int("meili_dog.search.hits", len(result.Hits))))
+	defer span.End()
 
-	// 构建响应
 	response := models.SearchResponse{
 		Hits:               h.convertHits(result.Hits),
 		EstimatedTotalHits: result.EstimatedTotalHits,
@@ -87,20 +228,66 @@ func (h *SearchHandler) Search(c *gin.Context) {
 		Offset:             req.Offset,
 		Page:               req.Page,
 		ProcessingTimeMs:   int(result.ProcessingTimeMs),
-		IndexUID:           h.indexUID,
+		IndexUID:           indexUID,
+		FacetDistribution:  result.FacetDistribution,
+		FacetStats:         result.FacetStats,
 	}
 
-	// 计算总页数
-	if result.EstimatedTotalHits > 0 {
+	// 计算总页数，游标分页没有页码
+	if result.EstimatedTotalHits > 0 && req.Cursor == "" {
 		response.TotalPages = int((result.EstimatedTotalHits + int64(req.Limit) - 1) / int64(req.Limit))
 	}
 
-	c.JSON(http.StatusOK, response)
+	return response
+}
+
+// buildSearchRequest 根据搜索请求构建 Meilisearch 搜索参数，tenantFilter 不为空时与用户过滤条件合并
+func (h *SearchHandler) buildSearchRequest(ctx context.Context, st *handlerState, indexUID string, req *models.SearchRequest, tenantFilter string) (*meilisearch.SearchRequest, error) {
+	// 计算偏移量
+	if err := applyPagination(req, st.defaults); err != nil {
+		return nil, err
+	}
+
+	// 构建搜索参数
+	searchRequest := &meilisearch.SearchRequest{
+		Query:  req.Query,
+		Limit:  int64(req.Limit),
+		Offset: int64(req.Offset),
+	}
+
+	// 应用优化参数
+	st.applyOptimizationParams(searchRequest, indexUID)
+	applyHighlightOverrides(searchRequest, req.Highlight)
+
+	// 应用过滤条件
+	filter, err := h.buildFilter(ctx, st, indexUID, req.Filters, req.FilterExpr)
+	if err != nil {
+		return nil, err
+	}
+	if filter = andFilters(tenantFilter, filter); filter != "" {
+		searchRequest.Filter = filter
+	}
+
+	// 应用排序
+	if len(req.Sort) > 0 {
+		searchRequest.Sort = req.Sort
+	}
+
+	// 应用分面
+	req.Facets = normalizeFacets(req.Facets)
+	if err := h.validateFacets(ctx, st, indexUID, req.Facets); err != nil {
+		return nil, err
+	}
+	if len(req.Facets) > 0 {
+		searchRequest.Facets = req.Facets
+	}
+
+	return searchRequest, nil
 }
 
 // applyOptimizationParams 应用优化参数
-func (h *SearchHandler) applyOptimizationParams(req *meilisearch.SearchRequest) {
-	opt := h.config.Search.Optimization
+func (st *handlerState) applyOptimizationParams(req *meilisearch.SearchRequest, indexUID string) {
+	opt := st.indexes[indexUID]
 
 	if len(opt.AttributesToCrop) > 0 {
 		req.AttributesToCrop = opt.AttributesToCrop
@@ -122,52 +309,21 @@ func (h *SearchHandler) applyOptimizationParams(req *meilisearch.SearchRequest)
 	}
 }
 
-// applyFilters 应用过滤条件
-func (h *SearchHandler) applyFilters(req *meilisearch.SearchRequest, filters map[string]interface{}) {
-	if filters == nil || len(filters) == 0 {
+// applyHighlightOverrides 使用请求中的高亮参数覆盖配置
+func applyHighlightOverrides(req *meilisearch.SearchRequest, highlight *models.HighlightOptions) {
+	if highlight == nil {
 		return
 	}
 
-	filterStrings := h.buildFilterStrings(filters)
-	if len(filterStrings) > 0 {
-		req.Filter = filterStrings
+	if len(highlight.Attributes) > 0 {
+		req.AttributesToHighlight = highlight.Attributes
 	}
-}
-
-// buildFilterStrings 构建过滤字符串
-func (h *SearchHandler) buildFilterStrings(filters map[string]interface{}) []string {
-	var filterStrings []string
-
-	for field, value := range filters {
-		switch v := value.(type) {
-		case string:
-			filterStrings = append(filterStrings, field+" = '"+v+"'")
-		case int, int32, int64, float32, float64:
-			filterStrings = append(filterStrings, field+" = "+toString(v))
-		case bool:
-			filterStrings = append(filterStrings, field+" = "+strconv.FormatBool(v))
-		case []interface{}:
-			if len(v) > 0 {
-				filterStrings = append(filterStrings, h.buildArrayFilter(field, v))
-			}
-		}
+	if highlight.PreTag != "" {
+		req.HighlightPreTag = highlight.PreTag
 	}
-
-	return filterStrings
-}
-
-// buildArrayFilter 构建数组过滤条件
-func (h *SearchHandler) buildArrayFilter(field string, values []interface{}) string {
-	var conditions []string
-	for _, val := range values {
-		switch v := val.(type) {
-		case string:
-			conditions = append(conditions, field+" = '"+v+"'")
-		default:
-			conditions = append(conditions, field+" = "+toString(v))
-		}
+	if highlight.PostTag != "" {
+		req.HighlightPostTag = highlight.PostTag
 	}
-	return "(" + joinStrings(conditions, " OR ") + ")"
 }
 
 // convertHits 转换命中结果
@@ -201,23 +357,13 @@ func toString(v interface{}) string {
 	}
 }
 
-func joinStrings(strs []string, sep string) string {
-	if len(strs) == 0 {
-		return ""
-	}
-	if len(strs) == 1 {
-		return strs[0]
-	}
-	result := strs[0]
-	for _, s := range strs[1:] {
-		result += sep + s
-	}
-	return result
-}
-
 // HealthCheck 健康检查端点
 func (h *SearchHandler) HealthCheck(c *gin.Context) {
-	_, err := h.client.Health()
+	st := h.state()
+
+	call := startUpstream(c.Request.Context(), "health")
+	_, err := st.client.Health()
+	call.end(err)
 	if err != nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unhealthy", "error": err.Error()})
 		return
@@ -227,12 +373,14 @@ func (h *SearchHandler) HealthCheck(c *gin.Context) {
 
 // GetIndexInfo 获取当前配置的索引信息
 func (h *SearchHandler) GetIndexInfo(c *gin.Context) {
-	if h.indexUID == "" {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "索引未配置"})
+	st := h.state()
+	indexUID, err := st.resolveIndexUID(c)
+	if err != nil {
+		c.JSON(indexErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
-	index := h.client.Index(h.indexUID)
+	index := st.client.Index(indexUID)
 	stats, err := index.GetStats()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取索引统计信息失败: " + err.Error()})
@@ -247,7 +395,7 @@ func (h *SearchHandler) GetIndexInfo(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"index_uid": h.indexUID,
+		"index_uid": indexUID,
 		"stats":     stats,
 		"settings":  settings,
 	})
@@ -255,74 +403,33 @@ func (h *SearchHandler) GetIndexInfo(c *gin.Context) {
 
 // GetSettings 获取当前索引的所有设置
 func (h *SearchHandler) GetSettings(c *gin.Context) {
-	if h.indexUID == "" {
-		c.JSON(http.StatusBadRequest, models.SettingResponse{
+	st := h.state()
+	indexUID, err := st.resolveIndexUID(c)
+	if err != nil {
+		c.JSON(indexErrorStatus(err, http.StatusBadRequest), models.SettingResponse{
 			Success: false,
-			Error:   "索引未配置",
+			Error:   err.Error(),
 		})
 		return
 	}
 
-	index := h.client.Index(h.indexUID)
-
-	// 获取各种设置
-	searchableAttrs, err := index.GetSearchableAttributes()
-	if err != nil {
-		log.Printf("获取可搜索字段错误: %v", err)
-	}
-
-	filterableAttrs, err := index.GetFilterableAttributes()
-	if err != nil {
-		log.Printf("获取可过滤字段错误: %v", err)
-	}
-
-	sortableAttrs, err := index.GetSortableAttributes()
-	if err != nil {
-		log.Printf("获取可排序字段错误: %v", err)
-	}
-
-	rankingRules, err := index.GetRankingRules()
-	if err != nil {
-		log.Printf("获取排序规则错误: %v", err)
-	}
-
-	displayedAttrs, err := index.GetDisplayedAttributes()
-	if err != nil {
-		log.Printf("获取显示字段错误: %v", err)
-	}
-
-	stopWords, err := index.GetStopWords()
+	settings, err := h.currentSettings(c.Request.Context(), st, indexUID)
 	if err != nil {
-		log.Printf("获取停用词错误: %v", err)
-	}
-
-	synonyms, err := index.GetSynonyms()
-	if err != nil {
-		log.Printf("获取同义词错误: %v", err)
-	}
-
-	typoTolerance, err := index.GetTypoTolerance()
-	if err != nil {
-		log.Printf("获取拼写容错设置错误: %v", err)
-	}
-
-	// 创建响应对象，处理指针类型
-	response := models.CurrentSettingsResponse{
-		SearchableAttributes: derefStringSlice(searchableAttrs),
-		FilterableAttributes: derefStringSlice(filterableAttrs),
-		SortableAttributes:   derefStringSlice(sortableAttrs),
-		RankingRules:         derefStringSlice(rankingRules),
-		DisplayedAttributes:  derefStringSlice(displayedAttrs),
-		StopWords:            derefStringSlice(stopWords),
-		Synonyms:             derefStringMap(synonyms),
-		TypoTolerance:        typoTolerance,
+		logError(c, "获取设置错误", err, "index", indexUID)
+		c.JSON(upstreamErrorStatus(err), models.SettingResponse{
+			Success: false,
+			Error:   "获取设置失败: " + err.Error(),
+		})
+		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, settings)
 }
 
 // UpdateSearchableAttributes 设置可搜索字段及其权重
 func (h *SearchHandler) UpdateSearchableAttributes(c *gin.Context) {
+	st := h.state()
+
 	var req models.SettingRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, models.SettingResponse{
@@ -332,15 +439,16 @@ func (h *SearchHandler) UpdateSearchableAttributes(c *gin.Context) {
 		return
 	}
 
-	if h.indexUID == "" {
-		c.JSON(http.StatusBadRequest, models.SettingResponse{
+	indexUID, err := st.resolveIndexUID(c)
+	if err != nil {
+		c.JSON(indexErrorStatus(err, http.StatusBadRequest), models.SettingResponse{
 			Success: false,
-			Error:   "索引未配置",
+			Error:   err.Error(),
 		})
 		return
 	}
 
-	index := h.client.Index(h.indexUID)
+	index := st.client.Index(indexUID)
 
 	// 如果有权重信息，需要处理字段权重
 	searchableAttrs := req.SearchableAttributes
@@ -348,25 +456,49 @@ func (h *SearchHandler) UpdateSearchableAttributes(c *gin.Context) {
 		searchableAttrs = h.applyWeightsToAttributes(req.SearchableAttributes, req.Weights)
 	}
 
+	snapshot, ok := h.snapshotBeforeUpdate(c, st, indexUID, "update_searchable_attributes")
+	if !ok {
+		return
+	}
+
+	call := startUpstream(c.Request.Context(), "update_searchable_attributes")
 	task, err := index.UpdateSearchableAttributes(&searchableAttrs)
+	call.end(err)
 	if err != nil {
-		log.Printf("更新可搜索字段错误: %v", err)
+		logError(c, "更新可搜索字段错误", err, "index", indexUID)
 		c.JSON(http.StatusInternalServerError, models.SettingResponse{
 			Success: false,
 			Error:   "更新失败: " + err.Error(),
 		})
 		return
 	}
+	h.invalidateSearchCache(indexUID, task.TaskUID)
+
+	state, err := h.waitTaskIfRequested(c, st, task.TaskUID)
+	if err != nil {
+		c.JSON(taskErrorStatus(err), models.SettingResponse{
+			Success:  false,
+			TaskUID:  task.TaskUID,
+			Task:     state,
+			Snapshot: snapshot.Version,
+			Error:    err.Error(),
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, models.SettingResponse{
-		Success: true,
-		Message: "可搜索字段更新成功",
-		TaskUID: task.TaskUID,
+		Success:  true,
+		Message:  "可搜索字段更新成功",
+		TaskUID:  task.TaskUID,
+		Task:     state,
+		Snapshot: snapshot.Version,
 	})
 }
 
 // UpdateFilterableAttributes 设置可过滤字段
 func (h *SearchHandler) UpdateFilterableAttributes(c *gin.Context) {
+	st := h.state()
+
 	var req models.SettingRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, models.SettingResponse{
@@ -376,35 +508,61 @@ func (h *SearchHandler) UpdateFilterableAttributes(c *gin.Context) {
 		return
 	}
 
-	if h.indexUID == "" {
-		c.JSON(http.StatusBadRequest, models.SettingResponse{
+	indexUID, err := st.resolveIndexUID(c)
+	if err != nil {
+		c.JSON(indexErrorStatus(err, http.StatusBadRequest), models.SettingResponse{
 			Success: false,
-			Error:   "索引未配置",
+			Error:   err.Error(),
 		})
 		return
 	}
 
-	index := h.client.Index(h.indexUID)
+	index := st.client.Index(indexUID)
+
+	snapshot, ok := h.snapshotBeforeUpdate(c, st, indexUID, "update_filterable_attributes")
+	if !ok {
+		return
+	}
 
+	call := startUpstream(c.Request.Context(), "update_filterable_attributes")
 	task, err := index.UpdateFilterableAttributes(&req.FilterableAttributes)
+	call.end(err)
 	if err != nil {
-		log.Printf("更新可过滤字段错误: %v", err)
+		logError(c, "更新可过滤字段错误", err, "index", indexUID)
 		c.JSON(http.StatusInternalServerError, models.SettingResponse{
 			Success: false,
 			Error:   "更新失败: " + err.Error(),
 		})
 		return
 	}
+	h.invalidateAttributes(indexUID)
+	h.invalidateSearchCache(indexUID, task.TaskUID)
+
+	state, err := h.waitTaskIfRequested(c, st, task.TaskUID)
+	if err != nil {
+		c.JSON(taskErrorStatus(err), models.SettingResponse{
+			Success:  false,
+			TaskUID:  task.TaskUID,
+			Task:     state,
+			Snapshot: snapshot.Version,
+			Error:    err.Error(),
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, models.SettingResponse{
-		Success: true,
-		Message: "可过滤字段更新成功",
-		TaskUID: task.TaskUID,
+		Success:  true,
+		Message:  "可过滤字段更新成功",
+		TaskUID:  task.TaskUID,
+		Task:     state,
+		Snapshot: snapshot.Version,
 	})
 }
 
 // UpdateSortableAttributes 设置可排序字段
 func (h *SearchHandler) UpdateSortableAttributes(c *gin.Context) {
+	st := h.state()
+
 	var req models.SettingRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, models.SettingResponse{
@@ -414,35 +572,61 @@ func (h *SearchHandler) UpdateSortableAttributes(c *gin.Context) {
 		return
 	}
 
-	if h.indexUID == "" {
-		c.JSON(http.StatusBadRequest, models.SettingResponse{
+	indexUID, err := st.resolveIndexUID(c)
+	if err != nil {
+		c.JSON(indexErrorStatus(err, http.StatusBadRequest), models.SettingResponse{
 			Success: false,
-			Error:   "索引未配置",
+			Error:   err.Error(),
 		})
 		return
 	}
 
-	index := h.client.Index(h.indexUID)
+	index := st.client.Index(indexUID)
+
+	snapshot, ok := h.snapshotBeforeUpdate(c, st, indexUID, "update_sortable_attributes")
+	if !ok {
+		return
+	}
 
+	call := startUpstream(c.Request.Context(), "update_sortable_attributes")
 	task, err := index.UpdateSortableAttributes(&req.SortableAttributes)
+	call.end(err)
 	if err != nil {
-		log.Printf("更新可排序字段错误: %v", err)
+		logError(c, "更新可排序字段错误", err, "index", indexUID)
 		c.JSON(http.StatusInternalServerError, models.SettingResponse{
 			Success: false,
 			Error:   "更新失败: " + err.Error(),
 		})
 		return
 	}
+	h.invalidateAttributes(indexUID)
+	h.invalidateSearchCache(indexUID, task.TaskUID)
+
+	state, err := h.waitTaskIfRequested(c, st, task.TaskUID)
+	if err != nil {
+		c.JSON(taskErrorStatus(err), models.SettingResponse{
+			Success:  false,
+			TaskUID:  task.TaskUID,
+			Task:     state,
+			Snapshot: snapshot.Version,
+			Error:    err.Error(),
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, models.SettingResponse{
-		Success: true,
-		Message: "可排序字段更新成功",
-		TaskUID: task.TaskUID,
+		Success:  true,
+		Message:  "可排序字段更新成功",
+		TaskUID:  task.TaskUID,
+		Task:     state,
+		Snapshot: snapshot.Version,
 	})
 }
 
 // UpdateRankingRules 更新排序规则
 func (h *SearchHandler) UpdateRankingRules(c *gin.Context) {
+	st := h.state()
+
 	var req models.SettingRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, models.SettingResponse{
@@ -452,59 +636,107 @@ func (h *SearchHandler) UpdateRankingRules(c *gin.Context) {
 		return
 	}
 
-	if h.indexUID == "" {
-		c.JSON(http.StatusBadRequest, models.SettingResponse{
+	indexUID, err := st.resolveIndexUID(c)
+	if err != nil {
+		c.JSON(indexErrorStatus(err, http.StatusBadRequest), models.SettingResponse{
 			Success: false,
-			Error:   "索引未配置",
+			Error:   err.Error(),
 		})
 		return
 	}
 
-	index := h.client.Index(h.indexUID)
+	index := st.client.Index(indexUID)
 
+	snapshot, ok := h.snapshotBeforeUpdate(c, st, indexUID, "update_ranking_rules")
+	if !ok {
+		return
+	}
+
+	call := startUpstream(c.Request.Context(), "update_ranking_rules")
 	task, err := index.UpdateRankingRules(&req.RankingRules)
+	call.end(err)
 	if err != nil {
-		log.Printf("更新排序规则错误: %v", err)
+		logError(c, "更新排序规则错误", err, "index", indexUID)
 		c.JSON(http.StatusInternalServerError, models.SettingResponse{
 			Success: false,
 			Error:   "更新失败: " + err.Error(),
 		})
 		return
 	}
+	h.invalidateSearchCache(indexUID, task.TaskUID)
+
+	state, err := h.waitTaskIfRequested(c, st, task.TaskUID)
+	if err != nil {
+		c.JSON(taskErrorStatus(err), models.SettingResponse{
+			Success:  false,
+			TaskUID:  task.TaskUID,
+			Task:     state,
+			Snapshot: snapshot.Version,
+			Error:    err.Error(),
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, models.SettingResponse{
-		Success: true,
-		Message: "排序规则更新成功",
-		TaskUID: task.TaskUID,
+		Success:  true,
+		Message:  "排序规则更新成功",
+		TaskUID:  task.TaskUID,
+		Task:     state,
+		Snapshot: snapshot.Version,
 	})
 }
 
 // ResetSettings 重置所有设置为默认值
 func (h *SearchHandler) ResetSettings(c *gin.Context) {
-	if h.indexUID == "" {
-		c.JSON(http.StatusBadRequest, models.SettingResponse{
+	st := h.state()
+	indexUID, err := st.resolveIndexUID(c)
+	if err != nil {
+		c.JSON(indexErrorStatus(err, http.StatusBadRequest), models.SettingResponse{
 			Success: false,
-			Error:   "索引未配置",
+			Error:   err.Error(),
 		})
 		return
 	}
 
-	index := h.client.Index(h.indexUID)
+	index := st.client.Index(indexUID)
 
+	snapshot, ok := h.snapshotBeforeUpdate(c, st, indexUID, "reset")
+	if !ok {
+		return
+	}
+
+	call := startUpstream(c.Request.Context(), "reset_settings")
 	task, err := index.ResetSettings()
+	call.end(err)
 	if err != nil {
-		log.Printf("重置设置错误: %v", err)
+		logError(c, "重置设置错误", err, "index", indexUID)
 		c.JSON(http.StatusInternalServerError, models.SettingResponse{
 			Success: false,
 			Error:   "重置失败: " + err.Error(),
 		})
 		return
 	}
+	h.invalidateAttributes(indexUID)
+	h.invalidateSearchCache(indexUID, task.TaskUID)
+
+	state, err := h.waitTaskIfRequested(c, st, task.TaskUID)
+	if err != nil {
+		c.JSON(taskErrorStatus(err), models.SettingResponse{
+			Success:  false,
+			TaskUID:  task.TaskUID,
+			Task:     state,
+			Snapshot: snapshot.Version,
+			Error:    err.Error(),
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, models.SettingResponse{
-		Success: true,
-		Message: "设置重置成功",
-		TaskUID: task.TaskUID,
+		Success:  true,
+		Message:  "设置重置成功",
+		TaskUID:  task.TaskUID,
+		Task:     state,
+		Snapshot: snapshot.Version,
 	})
 }
 
@@ -530,11 +762,3 @@ func derefStringSlice(ptr *[]string) []string {
 	}
 	return *ptr
 }
-
-// 辅助函数：解引用字符串映射指针
-func derefStringMap(ptr *map[string][]string) map[string][]string {
-	if ptr == nil {
-		return map[string][]string{}
-	}
-	return *ptr
-}
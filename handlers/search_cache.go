@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"meili_dog/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/meilisearch/meilisearch-go"
+)
+
+const (
+	defaultSearchCacheEntries = 1000
+	defaultSearchCacheTTL     = 60 * time.Second
+	searchCacheTaskTimeout    = 10 * time.Minute       // 写入任务完成后再次清理缓存的最长等待时间
+	searchCachePollInterval   = 250 * time.Millisecond // 查询未完成写入任务的间隔
+	searchCacheHeader         = "X-Cache"
+)
+
+// searchCache 搜索结果的 LRU 缓存，条目超过 ttl 后失效
+// 缓存 Meilisearch 的原始结果，响应仍按当前请求构建
+// 索引有未完成的写入任务时不缓存该索引的结果；搜索期间清理过缓存的结果同样不缓存，避免写入旧结果
+type searchCache struct {
+	mu          sync.Mutex
+	maxEntries  int
+	ttl         time.Duration
+	entries     map[string]*list.Element
+	lru         *list.List            // 队首为最近使用的条目
+	generations map[string]uint64     // 每个索引的清理次数
+	flushes     uint64                // 清空整个缓存的次数
+	pending     map[int64]pendingTask // 未完成的写入任务，完成后再次清理对应索引
+	wake        chan struct{}         // 有新的写入任务时唤醒后台任务
+	watchOnce   sync.Once
+
+	hits          atomic.Int64
+	misses        atomic.Int64
+	evictions     atomic.Int64
+	invalidations atomic.Int64
+}
+
+type pendingTask struct {
+	indexUID string
+	deadline time.Time
+}
+
+type searchCacheEntry struct {
+	key       string
+	indexUID  string
+	result    *meilisearch.SearchResponse
+	expiresAt time.Time
+}
+
+// newSearchCache 未启用缓存时返回 nil，nil 缓存的方法都是空操作
+func newSearchCache(enabled bool, maxEntries, ttlSeconds int) *searchCache {
+	if !enabled {
+		return nil
+	}
+	if maxEntries <= 0 {
+		maxEntries = defaultSearchCacheEntries
+	}
+	ttl := defaultSearchCacheTTL
+	if ttlSeconds > 0 {
+		ttl = time.Duration(ttlSeconds) * time.Second
+	}
+	return &searchCache{
+		maxEntries:  maxEntries,
+		ttl:         ttl,
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+		generations: make(map[string]uint64),
+		pending:     make(map[int64]pendingTask),
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// searchCacheKey 根据最终发给 Meilisearch 的搜索参数生成缓存键
+// 查询词忽略大小写并合并空白，过滤、排序、分页和租户条件都已包含在搜索参数中
+func searchCacheKey(indexUID string, request *meilisearch.SearchRequest) string {
+	normalized := *request
+	normalized.Query = strings.Join(strings.Fields(strings.ToLower(request.Query)), " ")
+	data, err := json.Marshal(&normalized)
+	if err != nil {
+		return ""
+	}
+	return indexUID + "\n" + string(data)
+}
+
+func (sc *searchCache) get(key string) (*meilisearch.SearchResponse, bool) {
+	if sc == nil || key == "" {
+		return nil, false
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	elem, ok := sc.entries[key]
+	if !ok {
+		sc.misses.Add(1)
+		return nil, false
+	}
+	entry := elem.Value.(*searchCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		sc.remove(elem)
+		sc.misses.Add(1)
+		return nil, false
+	}
+	sc.lru.MoveToFront(elem)
+	sc.hits.Add(1)
+	return entry.result, true
+}
+
+// generation 返回索引当前的清理次数，搜索前读取并传给 set
+func (sc *searchCache) generation(indexUID string) uint64 {
+	if sc == nil {
+		return 0
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.flushes + sc.generations[indexUID]
+}
+
+// set 缓存搜索结果，搜索开始后清理过缓存或索引有未完成的写入任务时不缓存
+func (sc *searchCache) set(key, indexUID string, generation uint64, result *meilisearch.SearchResponse) {
+	if sc == nil || key == "" {
+		return
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if sc.flushes+sc.generations[indexUID] != generation {
+		return
+	}
+	for _, task := range sc.pending {
+		if task.indexUID == indexUID {
+			return
+		}
+	}
+
+	entry := &searchCacheEntry{key: key, indexUID: indexUID, result: result, expiresAt: time.Now().Add(sc.ttl)}
+	if elem, ok := sc.entries[key]; ok {
+		elem.Value = entry
+		sc.lru.MoveToFront(elem)
+		return
+	}
+	sc.entries[key] = sc.lru.PushFront(entry)
+	for sc.lru.Len() > sc.maxEntries {
+		sc.remove(sc.lru.Back())
+		sc.evictions.Add(1)
+	}
+}
+
+// invalidate 删除索引的所有缓存条目，indexUID 为空时清空缓存
+func (sc *searchCache) invalidate(indexUID string) {
+	if sc == nil {
+		return
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	sc.invalidateLocked(indexUID)
+}
+
+func (sc *searchCache) invalidateLocked(indexUID string) {
+	for elem := sc.lru.Front(); elem != nil; {
+		next := elem.Next()
+		if indexUID == "" || elem.Value.(*searchCacheEntry).indexUID == indexUID {
+			sc.remove(elem)
+		}
+		elem = next
+	}
+	if indexUID == "" {
+		sc.flushes++
+	} else {
+		sc.generations[indexUID]++
+	}
+	sc.invalidations.Add(1)
+}
+
+// track 清理索引的缓存并记录写入任务，任务完成或超时前不缓存该索引的结果
+func (sc *searchCache) track(indexUID string, taskUID int64) {
+	sc.mu.Lock()
+	sc.invalidateLocked(indexUID)
+	sc.pending[taskUID] = pendingTask{indexUID: indexUID, deadline: time.Now().Add(searchCacheTaskTimeout)}
+	sc.mu.Unlock()
+
+	select {
+	case sc.wake <- struct{}{}:
+	default:
+	}
+}
+
+// pendingTasks 返回未完成的写入任务UID
+func (sc *searchCache) pendingTasks() []int64 {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	uids := make([]int64, 0, len(sc.pending))
+	for uid := range sc.pending {
+		uids = append(uids, uid)
+	}
+	return uids
+}
+
+// finish 移除已完成和超时的写入任务并再次清理对应索引，返回超时的任务数
+func (sc *searchCache) finish(done []int64, now time.Time) int {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	for _, uid := range done {
+		if task, ok := sc.pending[uid]; ok {
+			delete(sc.pending, uid)
+			sc.invalidateLocked(task.indexUID)
+		}
+	}
+	expired := 0
+	for uid, task := range sc.pending {
+		if now.After(task.deadline) {
+			delete(sc.pending, uid)
+			sc.invalidateLocked(task.indexUID)
+			expired++
+		}
+	}
+	return expired
+}
+
+func (sc *searchCache) remove(elem *list.Element) {
+	sc.lru.Remove(elem)
+	delete(sc.entries, elem.Value.(*searchCacheEntry).key)
+}
+
+func (sc *searchCache) stats() models.SearchCacheStats {
+	if sc == nil {
+		return models.SearchCacheStats{}
+	}
+	sc.mu.Lock()
+	entries := sc.lru.Len()
+	sc.mu.Unlock()
+
+	return models.SearchCacheStats{
+		Enabled:       true,
+		Entries:       entries,
+		MaxEntries:    sc.maxEntries,
+		TTLSeconds:    int(sc.ttl / time.Second),
+		Hits:          sc.hits.Load(),
+		Misses:        sc.misses.Load(),
+		Evictions:     sc.evictions.Load(),
+		Invalidations: sc.invalidations.Load(),
+	}
+}
+
+// invalidateSearchCache 设置或文档写入后清理索引的搜索缓存
+// Meilisearch 异步执行写入任务，任务完成前不缓存该索引的结果，任务完成后再清理一次
+func (h *SearchHandler) invalidateSearchCache(indexUID string, taskUID int64) {
+	if h.cache == nil {
+		return
+	}
+	h.cache.track(indexUID, taskUID)
+	h.cache.watchOnce.Do(func() { go h.watchSearchCacheTasks() })
+}
+
+// watchSearchCacheTasks 后台等待写入任务完成，所有未完成的任务在一次请求中查询
+func (h *SearchHandler) watchSearchCacheTasks() {
+	for {
+		if len(h.cache.pendingTasks()) == 0 {
+			<-h.cache.wake
+			continue
+		}
+		time.Sleep(searchCachePollInterval)
+		h.pollSearchCacheTasks(h.cache.pendingTasks())
+	}
+}
+
+// pollSearchCacheTasks 查询已结束的写入任务，清理对应索引的缓存
+func (h *SearchHandler) pollSearchCacheTasks(uids []int64) {
+	if len(uids) == 0 {
+		return
+	}
+
+	ctx := context.Background()
+	call := startUpstream(ctx, "get_tasks")
+	result, err := h.state().client.GetTasks(&meilisearch.TasksQuery{
+		UIDS:     uids,
+		Limit:    int64(len(uids)),
+		Statuses: []meilisearch.TaskStatus{meilisearch.TaskStatusSucceeded, meilisearch.TaskStatusFailed, meilisearch.TaskStatusCanceled},
+	})
+	call.end(err)
+
+	var done []int64
+	if err != nil {
+		slog.Warn("查询写入任务状态失败，稍后重试", append([]any{"tasks", len(uids)}, errorAttrs(err)...)...)
+	} else {
+		for i := range result.Results {
+			done = append(done, result.Results[i].UID)
+		}
+	}
+	if expired := h.cache.finish(done, time.Now()); expired > 0 {
+		slog.Warn("等待写入任务完成超时，已清理对应索引的搜索缓存", "tasks", expired)
+	}
+}
+
+// SearchCacheStats 搜索缓存统计
+func (h *SearchHandler) SearchCacheStats() models.SearchCacheStats {
+	return h.cache.stats()
+}
+
+// GetSearchCache 获取搜索缓存统计
+func (h *SearchHandler) GetSearchCache(c *gin.Context) {
+	c.JSON(http.StatusOK, h.cache.stats())
+}
+
+// PurgeSearchCache 清空搜索缓存，index_uid 参数可只清理单个索引
+func (h *SearchHandler) PurgeSearchCache(c *gin.Context) {
+	st := h.state()
+
+	indexUID := c.Query("index_uid")
+	if indexUID != "" {
+		if err := st.checkIndexAllowed(indexUID); err != nil {
+			c.JSON(indexErrorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+			return
+		}
+	}
+	h.cache.invalidate(indexUID)
+	c.JSON(http.StatusOK, h.cache.stats())
+}
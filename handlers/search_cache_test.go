@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/meilisearch/meilisearch-go"
+)
+
+func TestSearchCache(t *testing.T) {
+	result := &meilisearch.SearchResponse{EstimatedTotalHits: 1}
+
+	t.Run("淘汰最久未使用的条目", func(t *testing.T) {
+		sc := newSearchCache(true, 2, 60)
+		sc.set("a", "users", sc.generation("users"), result)
+		sc.set("b", "users", sc.generation("users"), result)
+		sc.get("a")
+		sc.set("c", "users", sc.generation("users"), result)
+		if _, ok := sc.get("b"); ok {
+			t.Error("b 应被淘汰")
+		}
+		if _, ok := sc.get("a"); !ok {
+			t.Error("a 不应被淘汰")
+		}
+	})
+
+	t.Run("过期条目不命中", func(t *testing.T) {
+		sc := newSearchCache(true, 10, 60)
+		sc.set("a", "users", sc.generation("users"), result)
+		sc.entries["a"].Value.(*searchCacheEntry).expiresAt = time.Now().Add(-time.Second)
+		if _, ok := sc.get("a"); ok {
+			t.Error("过期条目不应命中")
+		}
+	})
+
+	t.Run("清理前开始的搜索不写入缓存", func(t *testing.T) {
+		sc := newSearchCache(true, 10, 60)
+		generation := sc.generation("users")
+		sc.invalidate("users")
+		sc.set("a", "users", generation, result)
+		if _, ok := sc.get("a"); ok {
+			t.Error("旧结果不应写入缓存")
+		}
+
+		other := sc.generation("products")
+		sc.invalidate("")
+		sc.set("b", "products", other, result)
+		if _, ok := sc.get("b"); ok {
+			t.Error("清空缓存前开始的搜索不应写入缓存")
+		}
+	})
+
+	t.Run("写入任务完成前不缓存该索引", func(t *testing.T) {
+		sc := newSearchCache(true, 10, 60)
+		sc.track("users", 7)
+		sc.set("a", "users", sc.generation("users"), result)
+		sc.set("b", "products", sc.generation("products"), result)
+		if _, ok := sc.get("a"); ok {
+			t.Error("有未完成写入任务的索引不应缓存")
+		}
+		if _, ok := sc.get("b"); !ok {
+			t.Error("其他索引应正常缓存")
+		}
+
+		generation := sc.generation("users")
+		sc.finish([]int64{7}, time.Now())
+		sc.set("c", "users", generation, result)
+		if _, ok := sc.get("c"); ok {
+			t.Error("任务完成前开始的搜索不应写入缓存")
+		}
+		sc.set("c", "users", sc.generation("users"), result)
+		if _, ok := sc.get("c"); !ok {
+			t.Error("任务完成后应恢复缓存")
+		}
+	})
+
+	t.Run("超时的写入任务不再等待", func(t *testing.T) {
+		sc := newSearchCache(true, 10, 60)
+		sc.track("users", 7)
+		if expired := sc.finish(nil, time.Now()); expired != 0 {
+			t.Fatalf("finish() = %d，期望 0", expired)
+		}
+		if expired := sc.finish(nil, time.Now().Add(searchCacheTaskTimeout+time.Second)); expired != 1 {
+			t.Fatalf("finish() = %d，期望 1", expired)
+		}
+		if len(sc.pendingTasks()) != 0 {
+			t.Errorf("pendingTasks() = %v，期望为空", sc.pendingTasks())
+		}
+	})
+
+	t.Run("未启用缓存", func(t *testing.T) {
+		var sc *searchCache
+		sc.set("a", "users", sc.generation("users"), result)
+		if _, ok := sc.get("a"); ok {
+			t.Error("nil 缓存不应命中")
+		}
+	})
+}
+
+func TestPollSearchCacheTasks(t *testing.T) {
+	meili := newFakeMeili(t)
+	meili.reply("GET /tasks", 200, `{"results":[{"uid":1,"indexUid":"users","status":"succeeded"}],"total":1,"limit":2,"from":1,"next":null}`)
+	cfg := testConfig(t, meili)
+	cfg.Cache.Enabled = true
+	h := NewSearchHandler(cfg)
+
+	h.cache.track("users", 1)
+	h.cache.track("products", 2)
+	h.pollSearchCacheTasks(h.cache.pendingTasks())
+
+	requests := meili.received("GET /tasks")
+	if len(requests) != 1 {
+		t.Fatalf("GET /tasks 调用 %d 次，期望 1 次", len(requests))
+	}
+	query, err := url.ParseQuery(requests[0].Query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uids := query.Get("uids"); uids != "1,2" && uids != "2,1" {
+		t.Errorf("uids = %q，期望包含两个任务", uids)
+	}
+	if got := h.cache.pendingTasks(); len(got) != 1 || got[0] != 2 {
+		t.Errorf("pendingTasks() = %v，期望 [2]", got)
+	}
+
+	result := &meilisearch.SearchResponse{}
+	h.cache.set("a", "users", h.cache.generation("users"), result)
+	if _, ok := h.cache.get("a"); !ok {
+		t.Error("任务完成后 users 应恢复缓存")
+	}
+	h.cache.set("b", "products", h.cache.generation("products"), result)
+	if _, ok := h.cache.get("b"); ok {
+		t.Error("products 仍有未完成的写入任务，不应缓存")
+	}
+
+	meili.reply("GET /tasks", 500, `{"message":"boom","code":"internal","type":"internal","link":""}`)
+	h.pollSearchCacheTasks(h.cache.pendingTasks())
+	if got := h.cache.pendingTasks(); len(got) != 1 {
+		t.Errorf("查询失败后 pendingTasks() = %v，期望保留任务", got)
+	}
+}
@@ -0,0 +1,434 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"meili_dog/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/meilisearch/meilisearch-go"
+)
+
+// builtinRankingRules Meilisearch 内置排序规则
+var builtinRankingRules = map[string]bool{
+	"words":     true,
+	"typo":      true,
+	"proximity": true,
+	"attribute": true,
+	"sort":      true,
+	"exactness": true,
+}
+
+// PatchSettings 部分更新索引设置，请求中出现的所有设置在一个任务中生效
+func (h *SearchHandler) PatchSettings(c *gin.Context) {
+	st := h.state()
+
+	var req models.SettingsPatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.SettingResponse{
+			Success: false,
+			Error:   "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	settings, err := buildSettingsPatch(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.SettingResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	indexUID, err := st.resolveIndexUID(c)
+	if err != nil {
+		c.JSON(indexErrorStatus(err, http.StatusBadRequest), models.SettingResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	snapshot, ok := h.snapshotBeforeUpdate(c, st, indexUID, "patch")
+	if !ok {
+		return
+	}
+
+	task, err := h.updateSettings(c.Request.Context(), st, indexUID, settings)
+	if err != nil {
+		logError(c, "更新设置错误", err, "index", indexUID)
+		c.JSON(upstreamErrorStatus(err), models.SettingResponse{
+			Success: false,
+			Error:   "更新失败: " + err.Error(),
+		})
+		return
+	}
+	h.invalidateAttributes(indexUID)
+	h.invalidateSearchCache(indexUID, task.TaskUID)
+
+	state, err := h.waitTaskIfRequested(c, st, task.TaskUID)
+	if err != nil {
+		c.JSON(taskErrorStatus(err), models.SettingResponse{
+			Success:  false,
+			TaskUID:  task.TaskUID,
+			Task:     state,
+			Settings: settings,
+			Snapshot: snapshot.Version,
+			Error:    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SettingResponse{
+		Success:  true,
+		Message:  "设置更新成功",
+		TaskUID:  task.TaskUID,
+		Task:     state,
+		Settings: settings,
+		Snapshot: snapshot.Version,
+	})
+}
+
+// updateSettings 以 Meilisearch 的字段名 PATCH 索引设置
+// SDK 的 Settings 结构缺少部分设置项，且会忽略 false 等零值，因此直接调用 HTTP API
+func (h *SearchHandler) updateSettings(ctx context.Context, st *handlerState, indexUID string, settings map[string]interface{}) (*meilisearch.TaskInfo, error) {
+	var task meilisearch.TaskInfo
+	path := "/indexes/" + url.PathEscape(indexUID) + "/settings"
+	if err := h.doRequest(ctx, st, http.MethodPatch, path, settings, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// meiliSettings Meilisearch 返回的完整设置
+type meiliSettings struct {
+	SearchableAttributes []string            `json:"searchableAttributes"`
+	FilterableAttributes []string            `json:"filterableAttributes"`
+	SortableAttributes   []string            `json:"sortableAttributes"`
+	RankingRules         []string            `json:"rankingRules"`
+	DisplayedAttributes  []string            `json:"displayedAttributes"`
+	StopWords            []string            `json:"stopWords"`
+	Synonyms             map[string][]string `json:"synonyms"`
+	TypoTolerance        *struct {
+		Enabled             *bool `json:"enabled"`
+		MinWordSizeForTypos *struct {
+			OneTypo  *int64 `json:"oneTypo"`
+			TwoTypos *int64 `json:"twoTypos"`
+		} `json:"minWordSizeForTypos"`
+		DisableOnWords      *[]string `json:"disableOnWords"`
+		DisableOnAttributes *[]string `json:"disableOnAttributes"`
+	} `json:"typoTolerance"`
+	DistinctAttribute *string `json:"distinctAttribute"`
+	Pagination        *struct {
+		MaxTotalHits *int64 `json:"maxTotalHits"`
+	} `json:"pagination"`
+	Faceting *struct {
+		MaxValuesPerFacet *int64            `json:"maxValuesPerFacet"`
+		SortFacetValuesBy map[string]string `json:"sortFacetValuesBy"`
+	} `json:"faceting"`
+	ProximityPrecision string   `json:"proximityPrecision"`
+	SeparatorTokens    []string `json:"separatorTokens"`
+	NonSeparatorTokens []string `json:"nonSeparatorTokens"`
+	Dictionary         []string `json:"dictionary"`
+}
+
+// currentSettings 一次读取索引的全部设置
+func (h *SearchHandler) currentSettings(ctx context.Context, st *handlerState, indexUID string) (*models.CurrentSettingsResponse, error) {
+	var raw meiliSettings
+	path := "/indexes/" + url.PathEscape(indexUID) + "/settings"
+	if err := h.doRequest(ctx, st, http.MethodGet, path, nil, &raw); err != nil {
+		return nil, err
+	}
+
+	settings := &models.CurrentSettingsResponse{
+		SearchableAttributes: normalizeList(raw.SearchableAttributes),
+		FilterableAttributes: normalizeList(raw.FilterableAttributes),
+		SortableAttributes:   normalizeList(raw.SortableAttributes),
+		RankingRules:         normalizeList(raw.RankingRules),
+		DisplayedAttributes:  normalizeList(raw.DisplayedAttributes),
+		StopWords:            normalizeList(raw.StopWords),
+		Synonyms:             raw.Synonyms,
+		DistinctAttribute:    raw.DistinctAttribute,
+		ProximityPrecision:   raw.ProximityPrecision,
+		SeparatorTokens:      raw.SeparatorTokens,
+		NonSeparatorTokens:   raw.NonSeparatorTokens,
+		Dictionary:           raw.Dictionary,
+	}
+	if settings.Synonyms == nil {
+		settings.Synonyms = map[string][]string{}
+	}
+
+	if typo := raw.TypoTolerance; typo != nil {
+		settings.TypoTolerance = &models.TypoToleranceSetting{
+			Enabled:             typo.Enabled,
+			DisableOnWords:      typo.DisableOnWords,
+			DisableOnAttributes: typo.DisableOnAttributes,
+		}
+		if size := typo.MinWordSizeForTypos; size != nil {
+			settings.TypoTolerance.MinWordSizeForTypos = &models.MinWordSizeForTypos{
+				OneTypo:  size.OneTypo,
+				TwoTypos: size.TwoTypos,
+			}
+		}
+	}
+	if raw.Pagination != nil {
+		settings.Pagination = &models.PaginationSetting{MaxTotalHits: raw.Pagination.MaxTotalHits}
+	}
+	if raw.Faceting != nil {
+		settings.Faceting = &models.FacetingSetting{
+			MaxValuesPerFacet: raw.Faceting.MaxValuesPerFacet,
+			SortFacetValuesBy: raw.Faceting.SortFacetValuesBy,
+		}
+	}
+	return settings, nil
+}
+
+// buildSettingsPatch 校验设置并转换为 Meilisearch 的字段名
+func buildSettingsPatch(req *models.SettingsPatchRequest) (map[string]interface{}, error) {
+	settings := make(map[string]interface{})
+
+	attributeLists := []struct {
+		name     string
+		key      string
+		values   *[]string
+		wildcard bool
+	}{
+		{"searchable_attributes", "searchableAttributes", req.SearchableAttributes, true},
+		{"filterable_attributes", "filterableAttributes", req.FilterableAttributes, false},
+		{"sortable_attributes", "sortableAttributes", req.SortableAttributes, false},
+		{"displayed_attributes", "displayedAttributes", req.DisplayedAttributes, true},
+	}
+	for _, list := range attributeLists {
+		if list.values == nil {
+			continue
+		}
+		if err := validateAttributeList(list.name, *list.values, list.wildcard); err != nil {
+			return nil, err
+		}
+		settings[list.key] = *list.values
+	}
+
+	if req.RankingRules != nil {
+		if err := validateRankingRules(*req.RankingRules); err != nil {
+			return nil, err
+		}
+		settings["rankingRules"] = *req.RankingRules
+	}
+
+	wordLists := []struct {
+		name   string
+		key    string
+		values *[]string
+	}{
+		{"stop_words", "stopWords", req.StopWords},
+		{"separator_tokens", "separatorTokens", req.SeparatorTokens},
+		{"non_separator_tokens", "nonSeparatorTokens", req.NonSeparatorTokens},
+		{"dictionary", "dictionary", req.Dictionary},
+	}
+	for _, list := range wordLists {
+		if list.values == nil {
+			continue
+		}
+		if err := validateWordList(list.name, *list.values); err != nil {
+			return nil, err
+		}
+		settings[list.key] = *list.values
+	}
+
+	if req.Synonyms != nil {
+		for word, synonyms := range *req.Synonyms {
+			if strings.TrimSpace(word) == "" {
+				return nil, fmt.Errorf("synonyms 的词不能为空")
+			}
+			if err := validateWordList("synonyms."+word, synonyms); err != nil {
+				return nil, err
+			}
+		}
+		settings["synonyms"] = *req.Synonyms
+	}
+
+	if req.TypoTolerance != nil {
+		typo, err := buildTypoTolerance(req.TypoTolerance)
+		if err != nil {
+			return nil, err
+		}
+		settings["typoTolerance"] = typo
+	}
+
+	if req.DistinctAttribute != nil {
+		if *req.DistinctAttribute == "" {
+			settings["distinctAttribute"] = nil
+		} else {
+			if !validAttributeName(*req.DistinctAttribute) {
+				return nil, fmt.Errorf("distinct_attribute 字段名不合法: %q", *req.DistinctAttribute)
+			}
+			settings["distinctAttribute"] = *req.DistinctAttribute
+		}
+	}
+
+	if req.Pagination != nil {
+		if req.Pagination.MaxTotalHits == nil || *req.Pagination.MaxTotalHits < 1 {
+			return nil, fmt.Errorf("pagination.max_total_hits 必须是正整数")
+		}
+		settings["pagination"] = map[string]interface{}{"maxTotalHits": *req.Pagination.MaxTotalHits}
+	}
+
+	if req.Faceting != nil {
+		faceting := make(map[string]interface{})
+		if req.Faceting.MaxValuesPerFacet != nil {
+			if *req.Faceting.MaxValuesPerFacet < 1 {
+				return nil, fmt.Errorf("faceting.max_values_per_facet 必须是正整数")
+			}
+			faceting["maxValuesPerFacet"] = *req.Faceting.MaxValuesPerFacet
+		}
+		if req.Faceting.SortFacetValuesBy != nil {
+			for facet, order := range req.Faceting.SortFacetValuesBy {
+				if facet != "*" && !validAttributeName(facet) {
+					return nil, fmt.Errorf("faceting.sort_facet_values_by 字段名不合法: %q", facet)
+				}
+				if order != "alpha" && order != "count" {
+					return nil, fmt.Errorf("faceting.sort_facet_values_by 只能是 alpha 或 count: %s", facet)
+				}
+			}
+			faceting["sortFacetValuesBy"] = req.Faceting.SortFacetValuesBy
+		}
+		if len(faceting) == 0 {
+			return nil, fmt.Errorf("faceting 不能为空")
+		}
+		settings["faceting"] = faceting
+	}
+
+	if req.ProximityPrecision != nil {
+		if *req.ProximityPrecision != "byWord" && *req.ProximityPrecision != "byAttribute" {
+			return nil, fmt.Errorf("proximity_precision 只能是 byWord 或 byAttribute")
+		}
+		settings["proximityPrecision"] = *req.ProximityPrecision
+	}
+
+	if len(settings) == 0 {
+		return nil, fmt.Errorf("没有需要更新的设置")
+	}
+	return settings, nil
+}
+
+// buildTypoTolerance 校验拼写容错设置
+func buildTypoTolerance(req *models.TypoToleranceSetting) (map[string]interface{}, error) {
+	typo := make(map[string]interface{})
+
+	if req.Enabled != nil {
+		typo["enabled"] = *req.Enabled
+	}
+
+	if size := req.MinWordSizeForTypos; size != nil {
+		minWordSize := make(map[string]interface{})
+		if size.OneTypo != nil {
+			if *size.OneTypo < 0 {
+				return nil, fmt.Errorf("typo_tolerance.min_word_size_for_typos.one_typo 不能为负数")
+			}
+			minWordSize["oneTypo"] = *size.OneTypo
+		}
+		if size.TwoTypos != nil {
+			if *size.TwoTypos < 0 {
+				return nil, fmt.Errorf("typo_tolerance.min_word_size_for_typos.two_typos 不能为负数")
+			}
+			minWordSize["twoTypos"] = *size.TwoTypos
+		}
+		if size.OneTypo != nil && size.TwoTypos != nil && *size.TwoTypos < *size.OneTypo {
+			return nil, fmt.Errorf("typo_tolerance.min_word_size_for_typos.two_typos 不能小于 one_typo")
+		}
+		typo["minWordSizeForTypos"] = minWordSize
+	}
+
+	if req.DisableOnWords != nil {
+		if err := validateWordList("typo_tolerance.disable_on_words", *req.DisableOnWords); err != nil {
+			return nil, err
+		}
+		typo["disableOnWords"] = *req.DisableOnWords
+	}
+
+	if req.DisableOnAttributes != nil {
+		if err := validateAttributeList("typo_tolerance.disable_on_attributes", *req.DisableOnAttributes, false); err != nil {
+			return nil, err
+		}
+		typo["disableOnAttributes"] = *req.DisableOnAttributes
+	}
+
+	if len(typo) == 0 {
+		return nil, fmt.Errorf("typo_tolerance 不能为空")
+	}
+	return typo, nil
+}
+
+// validateAttributeList 校验字段列表：字段名合法且不重复，wildcard 表示允许单独使用 *
+func validateAttributeList(name string, attributes []string, wildcard bool) error {
+	seen := make(map[string]bool, len(attributes))
+	for _, attr := range attributes {
+		if attr == "*" {
+			if !wildcard {
+				return fmt.Errorf("%s 不支持 *", name)
+			}
+			if len(attributes) > 1 {
+				return fmt.Errorf("%s 中 * 不能与其他字段同时使用", name)
+			}
+			continue
+		}
+		if !validAttributeName(attr) {
+			return fmt.Errorf("%s 字段名不合法: %q", name, attr)
+		}
+		if seen[attr] {
+			return fmt.Errorf("%s 字段重复: %s", name, attr)
+		}
+		seen[attr] = true
+	}
+	return nil
+}
+
+// validateRankingRules 校验排序规则：内置规则或 field:asc/field:desc，且不重复
+func validateRankingRules(rules []string) error {
+	seen := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		if !builtinRankingRules[rule] && !validCustomRankingRule(rule) {
+			return fmt.Errorf("ranking_rules 规则不合法: %q", rule)
+		}
+		if seen[rule] {
+			return fmt.Errorf("ranking_rules 规则重复: %s", rule)
+		}
+		seen[rule] = true
+	}
+	return nil
+}
+
+// validCustomRankingRule 自定义排序规则，如 release_date:desc
+func validCustomRankingRule(rule string) bool {
+	i := strings.LastIndex(rule, ":")
+	if i < 0 {
+		return false
+	}
+	order := rule[i+1:]
+	return (order == "asc" || order == "desc") && validAttributeName(rule[:i])
+}
+
+// validAttributeName 设置中的字段名：不为空且不含控制字符
+// 设置原样传给 Meilisearch，不会拼接进过滤表达式，允许非 ASCII 字段名；写入过滤条件的字段名使用 schema.FieldNamePattern 校验
+func validAttributeName(name string) bool {
+	if strings.TrimSpace(name) == "" {
+		return false
+	}
+	return strings.IndexFunc(name, unicode.IsControl) < 0
+}
+
+// validateWordList 校验词列表不包含空字符串
+func validateWordList(name string, words []string) error {
+	for _, word := range words {
+		if word == "" {
+			return fmt.Errorf("%s 不能包含空字符串", name)
+		}
+	}
+	return nil
+}
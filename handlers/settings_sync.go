@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"meili_dog/models"
+	"meili_dog/schema"
+)
+
+// declaredSettings 收集配置中声明了设置的索引，startupOnly 时只包含 apply_on_startup = true 的索引
+// 字段类型设置了 apply_settings 时，其中声明的可过滤和可排序字段作为未设置的 filterable_attributes 和 sortable_attributes
+func (st *handlerState) declaredSettings(startupOnly bool) map[string]*models.SettingsConfig {
+	declared := make(map[string]*models.SettingsConfig)
+
+	add := func(uid string, settings *models.SettingsConfig) {
+		if uid == "" {
+			return
+		}
+		settings = withSchemaSettings(settings, st.schemas[uid])
+		if settings == nil || (startupOnly && !settings.ApplyOnStartup) {
+			return
+		}
+		declared[uid] = settings
+	}
+
+	add(st.indexUID, st.config.Search.Settings)
+	for _, idx := range st.config.Indexes {
+		add(idx.UID, idx.Settings)
+	}
+	return declared
+}
+
+// withSchemaSettings 用字段类型补充未声明的可过滤和可排序字段，不修改配置中的设置
+// 字段类型未设置 apply_settings 时不补充，避免只声明了部分字段就覆盖 Meilisearch 中已有的设置
+func withSchemaSettings(settings *models.SettingsConfig, s *schema.Schema) *models.SettingsConfig {
+	if s == nil || !s.ApplySettings() || (len(s.Filterable()) == 0 && len(s.Sortable()) == 0) {
+		return settings
+	}
+
+	merged := models.SettingsConfig{}
+	if settings != nil {
+		merged = *settings
+	}
+	if merged.FilterableAttributes == nil && len(s.Filterable()) > 0 {
+		merged.FilterableAttributes = s.Filterable()
+	}
+	if merged.SortableAttributes == nil && len(s.Sortable()) > 0 {
+		merged.SortableAttributes = s.Sortable()
+	}
+	return &merged
+}
+
+// SyncSettings 将配置中声明的设置与 Meilisearch 当前设置比较，只更新有差异的设置项
+// dryRun 时只计算差异不写入；startupOnly 时只同步 apply_on_startup = true 的索引
+func (h *SearchHandler) SyncSettings(ctx context.Context, dryRun, startupOnly bool) ([]models.SettingsSyncResult, error) {
+	st := h.state()
+	declared := st.declaredSettings(startupOnly)
+
+	uids := make([]string, 0, len(declared))
+	for uid := range declared {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+
+	results := make([]models.SettingsSyncResult, 0, len(uids))
+	var failed int
+	for _, uid := range uids {
+		result := h.syncIndexSettings(ctx, st, uid, declared[uid], dryRun)
+		if result.Error != "" {
+			failed++
+		}
+		results = append(results, result)
+	}
+
+	if failed > 0 {
+		return results, fmt.Errorf("%d 个索引的设置同步失败", failed)
+	}
+	return results, nil
+}
+
+// syncIndexSettings 同步单个索引的设置
+func (h *SearchHandler) syncIndexSettings(ctx context.Context, st *handlerState, indexUID string, desired *models.SettingsConfig, dryRun bool) models.SettingsSyncResult {
+	result := models.SettingsSyncResult{IndexUID: indexUID, Changes: []models.SettingChange{}}
+
+	// 比较之前先校验配置中声明的所有设置，dry-run 与实际同步对无效设置的结果一致
+	full := desiredPatch(desired)
+	if _, err := buildSettingsPatch(full); err != nil {
+		result.Error = "配置中的设置无效: " + err.Error()
+		return result
+	}
+
+	call := startUpstream(ctx, "get_settings")
+	current, err := st.client.Index(indexUID).GetSettings()
+	call.end(err)
+	if err != nil {
+		result.Error = "获取当前设置失败: " + err.Error()
+		return result
+	}
+
+	patch := models.SettingsPatchRequest{}
+
+	if full.SearchableAttributes != nil && !reflect.DeepEqual(normalizeList(current.SearchableAttributes), normalizeList(*full.SearchableAttributes)) {
+		result.Changes = append(result.Changes, settingChange("searchable_attributes", current.SearchableAttributes, *full.SearchableAttributes))
+		patch.SearchableAttributes = full.SearchableAttributes
+	}
+
+	if desired.FilterableAttributes != nil && !sameSet(current.FilterableAttributes, desired.FilterableAttributes) {
+		result.Changes = append(result.Changes, settingChange("filterable_attributes", current.FilterableAttributes, desired.FilterableAttributes))
+		patch.FilterableAttributes = &desired.FilterableAttributes
+	}
+
+	if desired.SortableAttributes != nil && !sameSet(current.SortableAttributes, desired.SortableAttributes) {
+		result.Changes = append(result.Changes, settingChange("sortable_attributes", current.SortableAttributes, desired.SortableAttributes))
+		patch.SortableAttributes = &desired.SortableAttributes
+	}
+
+	if desired.RankingRules != nil && !reflect.DeepEqual(normalizeList(current.RankingRules), normalizeList(desired.RankingRules)) {
+		result.Changes = append(result.Changes, settingChange("ranking_rules", current.RankingRules, desired.RankingRules))
+		patch.RankingRules = &desired.RankingRules
+	}
+
+	if desired.StopWords != nil && !sameWords(current.StopWords, desired.StopWords) {
+		result.Changes = append(result.Changes, settingChange("stop_words", current.StopWords, desired.StopWords))
+		patch.StopWords = &desired.StopWords
+	}
+
+	if desired.Synonyms != nil && !sameSynonyms(current.Synonyms, desired.Synonyms) {
+		result.Changes = append(result.Changes, settingChange("synonyms", current.Synonyms, desired.Synonyms))
+		patch.Synonyms = &desired.Synonyms
+	}
+
+	if len(result.Changes) == 0 || dryRun {
+		return result
+	}
+
+	settings, err := buildSettingsPatch(&patch)
+	if err != nil {
+		result.Error = "配置中的设置无效: " + err.Error()
+		return result
+	}
+
+	snapshot, err := h.snapshotSettings(ctx, st, indexUID, "sync")
+	if err != nil {
+		result.Error = "保存设置快照失败: " + err.Error()
+		return result
+	}
+	result.Snapshot = snapshot.Version
+
+	task, err := h.updateSettings(ctx, st, indexUID, settings)
+	if err != nil {
+		result.Error = "更新设置失败: " + err.Error()
+		return result
+	}
+	h.invalidateAttributes(indexUID)
+	h.invalidateSearchCache(indexUID, task.TaskUID)
+
+	slog.InfoContext(ctx, "同步索引设置", "index", indexUID, "changes", len(result.Changes), "task_uid", task.TaskUID)
+	result.TaskUID = task.TaskUID
+	return result
+}
+
+// desiredPatch 配置中声明的所有设置项
+func desiredPatch(desired *models.SettingsConfig) *models.SettingsPatchRequest {
+	patch := &models.SettingsPatchRequest{}
+	if desired.SearchableAttributes != nil {
+		searchable := orderByWeight(desired.SearchableAttributes, desired.Weights)
+		patch.SearchableAttributes = &searchable
+	}
+	if desired.FilterableAttributes != nil {
+		patch.FilterableAttributes = &desired.FilterableAttributes
+	}
+	if desired.SortableAttributes != nil {
+		patch.SortableAttributes = &desired.SortableAttributes
+	}
+	if desired.RankingRules != nil {
+		patch.RankingRules = &desired.RankingRules
+	}
+	if desired.StopWords != nil {
+		patch.StopWords = &desired.StopWords
+	}
+	if desired.Synonyms != nil {
+		patch.Synonyms = &desired.Synonyms
+	}
+	return patch
+}
+
+// orderByWeight 按权重从高到低排列可搜索字段，Meilisearch 以字段顺序决定权重
+// 未设置权重的字段保持原有顺序排在最后
+func orderByWeight(attributes []string, weights map[string]int) []string {
+	ordered := append([]string{}, attributes...)
+	if len(weights) == 0 {
+		return ordered
+	}
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		wi, iok := weights[ordered[i]]
+		wj, jok := weights[ordered[j]]
+		if iok != jok {
+			return iok
+		}
+		return wi > wj
+	})
+	return ordered
+}
+
+func settingChange(name string, current, desired interface{}) models.SettingChange {
+	return models.SettingChange{Setting: name, Current: current, Desired: desired}
+}
+
+// normalizeList nil 与空列表视为相同
+func normalizeList(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// sameSet 比较两个列表是否包含相同的元素（不考虑顺序）
+func sameSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sortedA := append([]string{}, a...)
+	sortedB := append([]string{}, b...)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+	return reflect.DeepEqual(sortedA, sortedB)
+}
+
+// normalizeWords 按 Meilisearch 保存停用词和同义词的方式处理：去掉首尾空白、转为小写、去重并排序
+func normalizeWords(words []string) []string {
+	normalized := make([]string, 0, len(words))
+	for _, word := range words {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if !slices.Contains(normalized, word) {
+			normalized = append(normalized, word)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+// sameWords 比较停用词，不考虑顺序、大小写和重复
+func sameWords(a, b []string) bool {
+	return reflect.DeepEqual(normalizeWords(a), normalizeWords(b))
+}
+
+// normalizeSynonyms 处理同义词中的词和同义词，大小写不同的词合并
+func normalizeSynonyms(synonyms map[string][]string) map[string][]string {
+	merged := make(map[string][]string, len(synonyms))
+	for word, values := range synonyms {
+		word = strings.ToLower(strings.TrimSpace(word))
+		merged[word] = append(merged[word], values...)
+	}
+	for word, values := range merged {
+		merged[word] = normalizeWords(values)
+	}
+	return merged
+}
+
+// sameSynonyms 比较同义词设置，不考虑同义词顺序、大小写和重复
+func sameSynonyms(a, b map[string][]string) bool {
+	return reflect.DeepEqual(normalizeSynonyms(a), normalizeSynonyms(b))
+}
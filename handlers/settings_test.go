@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// settingsMeili 返回空设置并接受设置更新的 fakeMeili
+func settingsMeili(t *testing.T) *fakeMeili {
+	t.Helper()
+	meili := newFakeMeili(t)
+	meili.reply("GET /indexes/users/settings", http.StatusOK, `{"searchableAttributes":["*"],"filterableAttributes":[],"sortableAttributes":[],"rankingRules":["words"],"displayedAttributes":["*"],"stopWords":[],"synonyms":{}}`)
+	meili.reply("PATCH /indexes/users/settings", http.StatusAccepted, `{"taskUid":7,"indexUid":"users","status":"enqueued","type":"settingsUpdate"}`)
+	return meili
+}
+
+func TestPatchSettings(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantPatch  string // Meilisearch 收到的设置，为空表示不应发送
+	}{
+		{
+			name:       "多个设置一次更新",
+			body:       `{"filterable_attributes":["genre","price"],"ranking_rules":["words","price:asc"],"stop_words":["the"],"distinct_attribute":"sku","typo_tolerance":{"min_word_size_for_typos":{"one_typo":4,"two_typos":8}}}`,
+			wantStatus: http.StatusOK,
+			wantPatch:  `{"filterableAttributes":["genre","price"],"rankingRules":["words","price:asc"],"stopWords":["the"],"distinctAttribute":"sku","typoTolerance":{"minWordSizeForTypos":{"oneTypo":4,"twoTypos":8}}}`,
+		},
+		{
+			name:       "非 ASCII 字段名",
+			body:       `{"searchable_attributes":["标题"],"distinct_attribute":"商品编号","ranking_rules":["价格:desc"],"faceting":{"sort_facet_values_by":{"分类":"count"}}}`,
+			wantStatus: http.StatusOK,
+			wantPatch:  `{"searchableAttributes":["标题"],"distinctAttribute":"商品编号","rankingRules":["价格:desc"],"faceting":{"sortFacetValuesBy":{"分类":"count"}}}`,
+		},
+		{
+			name:       "取消去重字段",
+			body:       `{"distinct_attribute":""}`,
+			wantStatus: http.StatusOK,
+			wantPatch:  `{"distinctAttribute":null}`,
+		},
+		{name: "没有设置", body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "字段重复", body: `{"filterable_attributes":["genre","genre"]}`, wantStatus: http.StatusBadRequest},
+		{name: "通配符与字段同时使用", body: `{"searchable_attributes":["*","title"]}`, wantStatus: http.StatusBadRequest},
+		{name: "不支持通配符", body: `{"sortable_attributes":["*"]}`, wantStatus: http.StatusBadRequest},
+		{name: "字段名包含控制字符", body: `{"displayed_attributes":["ti\ntle"]}`, wantStatus: http.StatusBadRequest},
+		{name: "空字段名", body: `{"distinct_attribute":" "}`, wantStatus: http.StatusBadRequest},
+		{name: "排序规则不合法", body: `{"ranking_rules":["price:up"]}`, wantStatus: http.StatusBadRequest},
+		{name: "排序规则重复", body: `{"ranking_rules":["words","words"]}`, wantStatus: http.StatusBadRequest},
+		{name: "停用词为空", body: `{"stop_words":[""]}`, wantStatus: http.StatusBadRequest},
+		{name: "同义词为空", body: `{"synonyms":{" ":["a"]}}`, wantStatus: http.StatusBadRequest},
+		{name: "容错词长度倒置", body: `{"typo_tolerance":{"min_word_size_for_typos":{"one_typo":8,"two_typos":4}}}`, wantStatus: http.StatusBadRequest},
+		{name: "分页上限不合法", body: `{"pagination":{"max_total_hits":0}}`, wantStatus: http.StatusBadRequest},
+		{name: "分面排序不合法", body: `{"faceting":{"sort_facet_values_by":{"genre":"random"}}}`, wantStatus: http.StatusBadRequest},
+		{name: "邻近精度不合法", body: `{"proximity_precision":"byLetter"}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meili := settingsMeili(t)
+			h := NewSearchHandler(testConfig(t, meili))
+			router := testRouter(t, func(r gin.IRoutes) { r.PATCH("/settings", h.PatchSettings) })
+
+			w := do(router, http.MethodPatch, "/settings", "admin-key", tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("状态码 = %d，期望 %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			patches := meili.received("PATCH /indexes/users/settings")
+			if tt.wantPatch == "" {
+				if len(patches) != 0 {
+					t.Fatalf("校验失败时不应更新设置，实际发送 %s", patches[0].Body)
+				}
+				return
+			}
+			if len(patches) != 1 {
+				t.Fatalf("发送 %d 次设置更新，期望 1 次", len(patches))
+			}
+			assertJSONEqual(t, patches[0].Body, tt.wantPatch)
+		})
+	}
+}
+
+// assertJSONEqual 比较两个 JSON 文本是否表示相同的值
+func assertJSONEqual(t *testing.T, got, want string) {
+	t.Helper()
+	var gotValue, wantValue interface{}
+	if err := json.Unmarshal([]byte(got), &gotValue); err != nil {
+		t.Fatalf("解析 %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("解析 %s: %v", want, err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("JSON = %s，期望 %s", got, want)
+	}
+}
@@ -0,0 +1,485 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"meili_dog/models"
+
+	"github.com/BurntSushi/toml"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSnapshotDir          = "data/snapshots" // 未配置 snapshots.dir 时的快照目录
+	defaultMaxSnapshotsPerIndex = 50               // 未配置 snapshots.max_per_index 时每个索引保留的快照数
+	currentSnapshotRef          = "current"        // 表示当前设置而不是某个快照
+)
+
+var (
+	errSnapshotNotFound       = errors.New("快照不存在")
+	errInvalidSnapshotVersion = errors.New("快照版本号无效")
+)
+
+// snapshotStore 设置快照存储，每个索引一个目录，每个快照一个 JSON 文件
+// 版本号在索引内递增，超过保留数量时删除最旧的快照
+type snapshotStore struct {
+	mu  sync.Mutex
+	dir string
+	max int
+}
+
+func newSnapshotStore(dir string, max int) *snapshotStore {
+	if dir == "" {
+		dir = defaultSnapshotDir
+	}
+	if max <= 0 {
+		max = defaultMaxSnapshotsPerIndex
+	}
+	return &snapshotStore{dir: dir, max: max}
+}
+
+func (s *snapshotStore) indexDir(indexUID string) string {
+	return filepath.Join(s.dir, indexUID)
+}
+
+func (s *snapshotStore) path(indexUID string, version int64) string {
+	return filepath.Join(s.indexDir(indexUID), fmt.Sprintf("%06d.json", version))
+}
+
+// versions 返回索引已有的快照版本，从旧到新排列
+func (s *snapshotStore) versions(indexUID string) ([]int64, error) {
+	entries, err := os.ReadDir(s.indexDir(indexUID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []int64
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		version, err := strconv.ParseInt(strings.TrimSuffix(name, ".json"), 10, 64)
+		if err != nil || version <= 0 {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions, nil
+}
+
+// save 保存新快照，先写临时文件再重命名，避免留下不完整的快照
+func (s *snapshotStore) save(indexUID, reason string, settings *models.CurrentSettingsResponse) (*models.SettingsSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.indexDir(indexUID), 0o755); err != nil {
+		return nil, err
+	}
+	versions, err := s.versions(indexUID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &models.SettingsSnapshot{
+		Version:   1,
+		IndexUID:  indexUID,
+		Reason:    reason,
+		CreatedAt: time.Now().UTC(),
+		Settings:  *settings,
+	}
+	if len(versions) > 0 {
+		snapshot.Version = versions[len(versions)-1] + 1
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(s.indexDir(indexUID), ".snapshot-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), s.path(indexUID, snapshot.Version)); err != nil {
+		return nil, err
+	}
+
+	versions = append(versions, snapshot.Version)
+	for len(versions) > s.max {
+		if err := os.Remove(s.path(indexUID, versions[0])); err != nil && !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("删除旧快照错误", "index", indexUID, "error", err)
+			break
+		}
+		versions = versions[1:]
+	}
+	return snapshot, nil
+}
+
+// load 读取指定版本的快照
+func (s *snapshotStore) load(indexUID string, version int64) (*models.SettingsSnapshot, error) {
+	data, err := os.ReadFile(s.path(indexUID, version))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %d", errSnapshotNotFound, version)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot models.SettingsSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("快照 %d 已损坏: %w", version, err)
+	}
+	return &snapshot, nil
+}
+
+// list 列出索引的快照，从新到旧排列
+func (s *snapshotStore) list(indexUID string) ([]models.SnapshotInfo, error) {
+	versions, err := s.versions(indexUID)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]models.SnapshotInfo, 0, len(versions))
+	for i := len(versions) - 1; i >= 0; i-- {
+		snapshot, err := s.load(indexUID, versions[i])
+		if err != nil {
+			slog.Warn("读取快照错误", "index", indexUID, "version", versions[i], "error", err)
+			continue
+		}
+		infos = append(infos, models.SnapshotInfo{
+			Version:   snapshot.Version,
+			Reason:    snapshot.Reason,
+			CreatedAt: snapshot.CreatedAt,
+		})
+	}
+	return infos, nil
+}
+
+// snapshotSettings 保存索引当前设置的快照
+func (h *SearchHandler) snapshotSettings(ctx context.Context, st *handlerState, indexUID, reason string) (*models.SettingsSnapshot, error) {
+	settings, err := h.currentSettings(ctx, st, indexUID)
+	if err != nil {
+		return nil, fmt.Errorf("获取当前设置失败: %w", err)
+	}
+	return h.snapshots.save(indexUID, reason, settings)
+}
+
+// snapshotBeforeUpdate 修改设置前保存快照，失败时写入错误响应并返回 false
+// 快照保存失败时不执行修改，保证每次修改都可以回滚
+func (h *SearchHandler) snapshotBeforeUpdate(c *gin.Context, st *handlerState, indexUID, reason string) (*models.SettingsSnapshot, bool) {
+	snapshot, err := h.snapshotSettings(c.Request.Context(), st, indexUID, reason)
+	if err != nil {
+		logError(c, "保存设置快照错误", err, "index", indexUID)
+		c.JSON(upstreamErrorStatus(err), models.SettingResponse{
+			Success: false,
+			Error:   "保存设置快照失败: " + err.Error(),
+		})
+		return nil, false
+	}
+	return snapshot, true
+}
+
+// ListSnapshots 列出索引的设置快照
+func (h *SearchHandler) ListSnapshots(c *gin.Context) {
+	st := h.state()
+	indexUID, err := st.resolveIndexUID(c)
+	if err != nil {
+		c.JSON(indexErrorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	snapshots, err := h.snapshots.list(indexUID)
+	if err != nil {
+		logError(c, "获取快照列表错误", err, "index", indexUID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取快照列表失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SnapshotListResponse{IndexUID: indexUID, Snapshots: snapshots})
+}
+
+// ExportSnapshot 导出单个快照，format 可选 json（默认）或 toml
+func (h *SearchHandler) ExportSnapshot(c *gin.Context) {
+	st := h.state()
+	indexUID, err := st.resolveIndexUID(c)
+	if err != nil {
+		c.JSON(indexErrorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	snapshot, err := h.loadSnapshot(indexUID, c.Param("version"))
+	if err != nil {
+		c.JSON(snapshotErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	switch format := c.DefaultQuery("format", "json"); format {
+	case "json":
+		c.JSON(http.StatusOK, snapshot)
+	case "toml":
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(snapshot); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "导出快照失败: " + err.Error()})
+			return
+		}
+		filename := fmt.Sprintf("%s-settings-%d.toml", indexUID, snapshot.Version)
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+		c.Data(http.StatusOK, "application/toml; charset=utf-8", buf.Bytes())
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format 只能是 json 或 toml: " + format})
+	}
+}
+
+// DiffSnapshots 比较两个快照，to 省略或为 current 时与当前设置比较
+func (h *SearchHandler) DiffSnapshots(c *gin.Context) {
+	st := h.state()
+	indexUID, err := st.resolveIndexUID(c)
+	if err != nil {
+		c.JSON(indexErrorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	from := c.Query("from")
+	if from == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少 from 参数"})
+		return
+	}
+	to := c.DefaultQuery("to", currentSnapshotRef)
+
+	fromSettings, err := h.snapshotRefSettings(c.Request.Context(), st, indexUID, from)
+	if err != nil {
+		c.JSON(snapshotErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	toSettings, err := h.snapshotRefSettings(c.Request.Context(), st, indexUID, to)
+	if err != nil {
+		c.JSON(snapshotErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	changes, err := diffSettings(fromSettings, toSettings)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "比较快照失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SnapshotDiffResponse{
+		IndexUID: indexUID,
+		From:     from,
+		To:       to,
+		Changes:  changes,
+	})
+}
+
+// RestoreSnapshot 将索引设置恢复到指定快照，恢复前同样会保存当前设置的快照
+func (h *SearchHandler) RestoreSnapshot(c *gin.Context) {
+	st := h.state()
+	indexUID, err := st.resolveIndexUID(c)
+	if err != nil {
+		c.JSON(indexErrorStatus(err, http.StatusBadRequest), models.SettingResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	target, err := h.loadSnapshot(indexUID, c.Param("version"))
+	if err != nil {
+		c.JSON(snapshotErrorStatus(err), models.SettingResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	settings, err := buildSettingsPatch(snapshotPatch(&target.Settings))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, models.SettingResponse{
+			Success: false,
+			Error:   "快照中的设置无效: " + err.Error(),
+		})
+		return
+	}
+
+	snapshot, ok := h.snapshotBeforeUpdate(c, st, indexUID, fmt.Sprintf("restore:%d", target.Version))
+	if !ok {
+		return
+	}
+
+	task, err := h.updateSettings(c.Request.Context(), st, indexUID, settings)
+	if err != nil {
+		logError(c, "恢复设置错误", err, "index", indexUID)
+		c.JSON(upstreamErrorStatus(err), models.SettingResponse{
+			Success:  false,
+			Snapshot: snapshot.Version,
+			Error:    "恢复失败: " + err.Error(),
+		})
+		return
+	}
+	h.invalidateAttributes(indexUID)
+	h.invalidateSearchCache(indexUID, task.TaskUID)
+
+	state, err := h.waitTaskIfRequested(c, st, task.TaskUID)
+	if err != nil {
+		c.JSON(taskErrorStatus(err), models.SettingResponse{
+			Success:  false,
+			TaskUID:  task.TaskUID,
+			Task:     state,
+			Snapshot: snapshot.Version,
+			Error:    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SettingResponse{
+		Success:  true,
+		Message:  fmt.Sprintf("已恢复到快照 %d", target.Version),
+		TaskUID:  task.TaskUID,
+		Task:     state,
+		Settings: settings,
+		Snapshot: snapshot.Version,
+	})
+}
+
+// loadSnapshot 解析版本号并读取快照
+func (h *SearchHandler) loadSnapshot(indexUID, raw string) (*models.SettingsSnapshot, error) {
+	version, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || version <= 0 {
+		return nil, fmt.Errorf("%w: %s", errInvalidSnapshotVersion, raw)
+	}
+	return h.snapshots.load(indexUID, version)
+}
+
+// snapshotRefSettings 读取快照或当前设置
+func (h *SearchHandler) snapshotRefSettings(ctx context.Context, st *handlerState, indexUID, ref string) (*models.CurrentSettingsResponse, error) {
+	if ref == currentSnapshotRef {
+		return h.currentSettings(ctx, st, indexUID)
+	}
+	snapshot, err := h.loadSnapshot(indexUID, ref)
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot.Settings, nil
+}
+
+// snapshotPatch 将快照转换为覆盖全部设置的更新请求
+// 快照中为 null 的较新设置项（旧版本 Meilisearch 不支持）保持不变
+func snapshotPatch(settings *models.CurrentSettingsResponse) *models.SettingsPatchRequest {
+	patch := &models.SettingsPatchRequest{
+		SearchableAttributes: &settings.SearchableAttributes,
+		FilterableAttributes: &settings.FilterableAttributes,
+		SortableAttributes:   &settings.SortableAttributes,
+		DisplayedAttributes:  &settings.DisplayedAttributes,
+		RankingRules:         &settings.RankingRules,
+		StopWords:            &settings.StopWords,
+		Synonyms:             &settings.Synonyms,
+		TypoTolerance:        settings.TypoTolerance,
+		Pagination:           settings.Pagination,
+		Faceting:             settings.Faceting,
+	}
+
+	// 没有去重字段时以空字符串表示取消
+	distinct := ""
+	if settings.DistinctAttribute != nil {
+		distinct = *settings.DistinctAttribute
+	}
+	patch.DistinctAttribute = &distinct
+
+	if settings.ProximityPrecision != "" {
+		patch.ProximityPrecision = &settings.ProximityPrecision
+	}
+	if settings.SeparatorTokens != nil {
+		patch.SeparatorTokens = &settings.SeparatorTokens
+	}
+	if settings.NonSeparatorTokens != nil {
+		patch.NonSeparatorTokens = &settings.NonSeparatorTokens
+	}
+	if settings.Dictionary != nil {
+		patch.Dictionary = &settings.Dictionary
+	}
+	return patch
+}
+
+// diffSettings 按设置项比较两份设置，返回有差异的设置项
+func diffSettings(from, to *models.CurrentSettingsResponse) ([]models.SnapshotChange, error) {
+	fromValues, err := settingsValues(from)
+	if err != nil {
+		return nil, err
+	}
+	toValues, err := settingsValues(to)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]bool, len(fromValues))
+	for key := range fromValues {
+		keys[key] = true
+	}
+	for key := range toValues {
+		keys[key] = true
+	}
+	names := make([]string, 0, len(keys))
+	for key := range keys {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+
+	changes := []models.SnapshotChange{}
+	for _, name := range names {
+		if !reflect.DeepEqual(fromValues[name], toValues[name]) {
+			changes = append(changes, models.SnapshotChange{Setting: name, From: fromValues[name], To: toValues[name]})
+		}
+	}
+	return changes, nil
+}
+
+// settingsValues 以 JSON 字段名展开设置，便于逐项比较
+func settingsValues(settings *models.CurrentSettingsResponse) (map[string]interface{}, error) {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// snapshotErrorStatus 版本号无效返回 400，快照不存在返回 404，其他错误透传 Meilisearch 状态码或返回 500
+func snapshotErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errInvalidSnapshotVersion):
+		return http.StatusBadRequest
+	case errors.Is(err, errSnapshotNotFound):
+		return http.StatusNotFound
+	default:
+		return upstreamErrorStatus(err)
+	}
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"meili_dog/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// settingsStore fakeMeili 中保存的索引设置，PATCH 按设置项合并
+type settingsStore struct {
+	mu       sync.Mutex
+	settings map[string]interface{}
+}
+
+func (s *settingsStore) register(meili *fakeMeili) {
+	meili.handle("GET /indexes/users/settings", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.settings)
+	})
+	meili.handle("PATCH /indexes/users/settings", func(w http.ResponseWriter, r *http.Request) {
+		var patch map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&patch)
+		s.mu.Lock()
+		for key, value := range patch {
+			s.settings[key] = value
+		}
+		s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		io.WriteString(w, `{"taskUid":1,"indexUid":"users","status":"enqueued","type":"settingsUpdate"}`)
+	})
+}
+
+func TestSnapshotRestoreAndDiff(t *testing.T) {
+	meili := newFakeMeili(t)
+	store := &settingsStore{settings: map[string]interface{}{
+		"searchableAttributes": []string{"标题", "描述"},
+		"filterableAttributes": []string{"分类"},
+		"sortableAttributes":   []string{},
+		"rankingRules":         []string{"words", "价格:desc"},
+		"displayedAttributes":  []string{"*"},
+		"stopWords":            []string{},
+		"synonyms":             map[string][]string{},
+		"distinctAttribute":    "商品编号",
+		"faceting":             map[string]interface{}{"maxValuesPerFacet": 100, "sortFacetValuesBy": map[string]string{"分类": "count"}},
+	}}
+	store.register(meili)
+
+	h := NewSearchHandler(testConfig(t, meili))
+	router := testRouter(t, func(r gin.IRoutes) {
+		r.PATCH("/settings", h.PatchSettings)
+		r.GET("/settings/snapshots", h.ListSnapshots)
+		r.GET("/settings/snapshots/diff", h.DiffSnapshots)
+		r.POST("/settings/snapshots/:version/restore", h.RestoreSnapshot)
+	})
+
+	// 修改设置前保存快照 1
+	w := do(router, http.MethodPatch, "/settings", "admin-key", `{"searchable_attributes":["title"],"distinct_attribute":""}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("更新设置: %d %s", w.Code, w.Body.String())
+	}
+
+	// 快照 1 与当前设置的差异
+	w = do(router, http.MethodGet, "/settings/snapshots/diff?from=1", "admin-key", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("比较快照: %d %s", w.Code, w.Body.String())
+	}
+	var diff models.SnapshotDiffResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &diff); err != nil {
+		t.Fatal(err)
+	}
+	var changed []string
+	for _, change := range diff.Changes {
+		changed = append(changed, change.Setting)
+	}
+	if diff.To != currentSnapshotRef || strings.Join(changed, ",") != "distinct_attribute,searchable_attributes" {
+		t.Errorf("差异 = %s，期望 distinct_attribute 和 searchable_attributes 相对当前设置有变化", w.Body.String())
+	}
+
+	// 恢复快照 1，恢复前保存快照 2，非 ASCII 字段名原样写回
+	w = do(router, http.MethodPost, "/settings/snapshots/1/restore", "admin-key", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("恢复快照: %d %s", w.Code, w.Body.String())
+	}
+	var restored models.SettingResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &restored); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Snapshot != 2 {
+		t.Errorf("恢复前保存的快照 = %d，期望 2", restored.Snapshot)
+	}
+	patches := meili.received("PATCH /indexes/users/settings")
+	if len(patches) != 2 {
+		t.Fatalf("发送 %d 次设置更新，期望 2 次", len(patches))
+	}
+	var sent map[string]interface{}
+	if err := json.Unmarshal([]byte(patches[1].Body), &sent); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{
+		"searchableAttributes": `["标题","描述"]`,
+		"rankingRules":         `["words","价格:desc"]`,
+		"distinctAttribute":    `"商品编号"`,
+		"faceting":             `{"maxValuesPerFacet":100,"sortFacetValuesBy":{"分类":"count"}}`,
+	} {
+		got, _ := json.Marshal(sent[key])
+		assertJSONEqual(t, string(got), want)
+	}
+
+	// 恢复后与快照 1 没有差异，与快照 2 的差异和第一次修改相反
+	w = do(router, http.MethodGet, "/settings/snapshots/diff?from=1", "admin-key", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &diff); err != nil || len(diff.Changes) != 0 {
+		t.Errorf("恢复后差异 = %s，期望没有差异", w.Body.String())
+	}
+	w = do(router, http.MethodGet, "/settings/snapshots/diff?from=2&to=1", "admin-key", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &diff); err != nil || len(diff.Changes) != 2 {
+		t.Errorf("快照 2 与快照 1 的差异 = %s，期望 2 项", w.Body.String())
+	}
+
+	// 快照列表从新到旧
+	w = do(router, http.MethodGet, "/settings/snapshots", "admin-key", "")
+	var list models.SnapshotListResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Snapshots) != 2 || list.Snapshots[0].Version != 2 || list.Snapshots[0].Reason != "restore:1" || list.Snapshots[1].Reason != "patch" {
+		t.Errorf("快照列表 = %s", w.Body.String())
+	}
+}
+
+func TestSnapshotErrors(t *testing.T) {
+	meili := newFakeMeili(t)
+	(&settingsStore{settings: map[string]interface{}{}}).register(meili)
+	h := NewSearchHandler(testConfig(t, meili))
+	router := testRouter(t, func(r gin.IRoutes) {
+		r.GET("/settings/snapshots/diff", h.DiffSnapshots)
+		r.POST("/settings/snapshots/:version/restore", h.RestoreSnapshot)
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		wantStatus int
+	}{
+		{name: "缺少 from", method: http.MethodGet, target: "/settings/snapshots/diff", wantStatus: http.StatusBadRequest},
+		{name: "版本号无效", method: http.MethodGet, target: "/settings/snapshots/diff?from=abc", wantStatus: http.StatusBadRequest},
+		{name: "比较不存在的快照", method: http.MethodGet, target: "/settings/snapshots/diff?from=9", wantStatus: http.StatusNotFound},
+		{name: "恢复不存在的快照", method: http.MethodPost, target: "/settings/snapshots/9/restore", wantStatus: http.StatusNotFound},
+		{name: "恢复版本号为 0", method: http.MethodPost, target: "/settings/snapshots/0/restore", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := do(router, tt.method, tt.target, "admin-key", ""); w.Code != tt.wantStatus {
+				t.Errorf("状态码 = %d，期望 %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if patches := meili.received("PATCH /indexes/users/settings"); len(patches) != 0 {
+				t.Errorf("出错时不应更新设置")
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"meili_dog/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/meilisearch/meilisearch-go"
+)
+
+const (
+	defaultTaskWaitTimeout = 30 * time.Second // wait=true 未指定 timeout 时的等待时间
+	maxTaskWaitTimeout     = 5 * time.Minute  // timeout 上限
+	taskPollInterval       = 100 * time.Millisecond
+	taskWaitKey            = "meili_dog.task_wait" // 上下文中保存等待时间的键
+)
+
+var (
+	errTaskFailed  = errors.New("任务执行失败")
+	errTaskTimeout = errors.New("等待任务完成超时")
+)
+
+// GetTask 获取单个任务状态
+func (h *SearchHandler) GetTask(c *gin.Context) {
+	st := h.state()
+
+	taskUID, err := strconv.ParseInt(c.Param("task_uid"), 10, 64)
+	if err != nil || taskUID < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "任务UID无效"})
+		return
+	}
+
+	call := startUpstream(c.Request.Context(), "get_task")
+	task, err := st.client.GetTask(taskUID)
+	call.end(err)
+	if err != nil {
+		logError(c, "获取任务错误", err, "task_uid", taskUID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取任务失败: " + err.Error()})
+		return
+	}
+
+	// 只允许查看配置的索引上的任务
+	if task.IndexUID == "" || st.checkIndexAllowed(task.IndexUID) != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, taskState(task))
+}
+
+// ListTasks 列出任务，可按状态、类型、索引过滤
+// 参数均支持逗号分隔的多个值，未指定索引时只返回允许访问的索引上的任务
+func (h *SearchHandler) ListTasks(c *gin.Context) {
+	st := h.state()
+
+	query := &meilisearch.TasksQuery{}
+
+	indexUIDs := splitQueryList(c.QueryArray("index_uid"))
+	for _, uid := range indexUIDs {
+		if err := st.checkIndexAllowed(uid); err != nil {
+			c.JSON(indexErrorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if len(indexUIDs) == 0 {
+		for uid := range st.indexes {
+			indexUIDs = append(indexUIDs, uid)
+		}
+	}
+	query.IndexUIDS = indexUIDs
+
+	for _, status := range splitQueryList(c.QueryArray("status")) {
+		query.Statuses = append(query.Statuses, meilisearch.TaskStatus(status))
+	}
+	for _, taskType := range splitQueryList(c.QueryArray("type")) {
+		query.Types = append(query.Types, meilisearch.TaskType(taskType))
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		value, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil || value < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit 必须是正整数"})
+			return
+		}
+		query.Limit = value
+	}
+	if from := c.Query("from"); from != "" {
+		value, err := strconv.ParseInt(from, 10, 64)
+		if err != nil || value < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from 必须是非负整数"})
+			return
+		}
+		query.From = value
+	}
+
+	call := startUpstream(c.Request.Context(), "list_tasks")
+	result, err := st.client.GetTasks(query)
+	call.end(err)
+	if err != nil {
+		logError(c, "获取任务列表错误", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取任务列表失败: " + err.Error()})
+		return
+	}
+
+	response := models.TaskListResponse{
+		Results: make([]*models.TaskState, 0, len(result.Results)),
+		Limit:   result.Limit,
+		From:    result.From,
+		Next:    result.Next,
+		Total:   result.Total,
+	}
+	for i := range result.Results {
+		response.Results = append(response.Results, taskState(&result.Results[i]))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// taskWaitTimeout 解析 wait/timeout 参数，未要求等待时返回 0
+// timeout 支持 Go 时间格式（如 10s、1m）或秒数
+func taskWaitTimeout(c *gin.Context) (time.Duration, error) {
+	wait, _ := strconv.ParseBool(c.Query("wait"))
+	if !wait {
+		return 0, nil
+	}
+
+	raw := c.Query("timeout")
+	if raw == "" {
+		return defaultTaskWaitTimeout, nil
+	}
+
+	timeout, err := time.ParseDuration(raw)
+	if err != nil {
+		seconds, convErr := strconv.Atoi(raw)
+		if convErr != nil {
+			return 0, fmt.Errorf("timeout 格式错误: %s", raw)
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("timeout 必须大于 0")
+	}
+	if timeout > maxTaskWaitTimeout {
+		timeout = maxTaskWaitTimeout
+	}
+	return timeout, nil
+}
+
+// TaskWaitParams 在执行写操作前校验 wait/timeout 参数，避免写入成功后才报参数错误
+func TaskWaitParams() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeout, err := taskWaitTimeout(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.Set(taskWaitKey, timeout)
+		c.Next()
+	}
+}
+
+// waitTaskIfRequested 请求带 wait=true 时等待任务完成并返回最终状态
+// 未要求等待时返回 nil, nil；任务失败或超时时同时返回最新状态和错误
+func (h *SearchHandler) waitTaskIfRequested(c *gin.Context, st *handlerState, taskUID int64) (*models.TaskState, error) {
+	timeout, ok := c.Value(taskWaitKey).(time.Duration)
+	if !ok {
+		var err error
+		if timeout, err = taskWaitTimeout(c); err != nil {
+			return nil, err
+		}
+	}
+	if timeout == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	return waitTask(ctx, st.client, taskUID)
+}
+
+// WaitTask 轮询任务直到完成或 ctx 结束，任务失败时同时返回最终状态和错误
+func (h *SearchHandler) WaitTask(ctx context.Context, taskUID int64) (*models.TaskState, error) {
+	return waitTask(ctx, h.state().client, taskUID)
+}
+
+func waitTask(ctx context.Context, client *meilisearch.Client, taskUID int64) (*models.TaskState, error) {
+	task, err := client.WaitForTask(taskUID, meilisearch.WaitParams{
+		Context:  ctx,
+		Interval: taskPollInterval,
+	})
+	if err != nil {
+		if !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+		// 超时后返回最新的任务状态，方便调用方继续轮询
+		latest, getErr := client.GetTask(taskUID)
+		if getErr != nil {
+			return nil, errTaskTimeout
+		}
+		return taskState(latest), errTaskTimeout
+	}
+
+	state := taskState(task)
+	if task.Status != meilisearch.TaskStatusSucceeded {
+		if state.Error != nil {
+			return state, fmt.Errorf("%w: %s", errTaskFailed, state.Error.Message)
+		}
+		return state, fmt.Errorf("%w: 状态 %s", errTaskFailed, task.Status)
+	}
+	return state, nil
+}
+
+// taskErrorStatus 等待任务出错时的HTTP状态码
+func taskErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errTaskTimeout):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, errTaskFailed):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// taskState 转换 Meilisearch 任务
+func taskState(task *meilisearch.Task) *models.TaskState {
+	uid := task.UID
+	if uid == 0 {
+		uid = task.TaskUID
+	}
+
+	state := &models.TaskState{
+		UID:        uid,
+		IndexUID:   task.IndexUID,
+		Status:     string(task.Status),
+		Type:       string(task.Type),
+		Duration:   task.Duration,
+		EnqueuedAt: task.EnqueuedAt,
+		Details:    task.Details,
+	}
+	if !task.StartedAt.IsZero() {
+		state.StartedAt = &task.StartedAt
+	}
+	if !task.FinishedAt.IsZero() {
+		state.FinishedAt = &task.FinishedAt
+	}
+	if task.Error.Code != "" || task.Error.Message != "" {
+		state.Error = &models.TaskError{
+			Message: task.Error.Message,
+			Code:    task.Error.Code,
+			Type:    task.Error.Type,
+			Link:    task.Error.Link,
+		}
+	}
+	return state
+}
+
+// splitQueryList 拆分重复或逗号分隔的查询参数
+func splitQueryList(values []string) []string {
+	var result []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				result = append(result, item)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"meili_dog/middleware"
+	"meili_dog/models"
+	"meili_dog/schema"
+
+	"github.com/gin-gonic/gin"
+	"github.com/meilisearch/meilisearch-go"
+)
+
+const (
+	defaultTenantField    = "tenant_id"
+	defaultTenantTokenTTL = 3600 // 租户令牌默认有效期（秒）
+)
+
+var errTenantRequired = errors.New("多租户模式下调用方必须属于某个租户")
+
+var errTenantDocumentWrite = errors.New("属于租户的调用方不能写入文档或按 ID 删除文档，只能按过滤条件删除本租户的文档")
+
+var errTenantInvalid = errors.New("租户无法用于过滤条件")
+
+var errTenantIndexWide = errors.New("属于租户的调用方不能访问索引级的设置、任务、缓存、配置和统计信息")
+
+// ValidateTenantConfig 校验多租户配置，启用多租户时必须同时启用认证
+func ValidateTenantConfig(cfg models.AppConfig) error {
+	if !cfg.Tenant.Enabled {
+		return nil
+	}
+	if len(cfg.Auth.Providers) == 0 {
+		return fmt.Errorf("启用 tenant 时必须配置 auth.providers")
+	}
+	if cfg.Auth.PublicSearch {
+		return fmt.Errorf("启用 tenant 时不能开启 auth.public_search")
+	}
+	if field := cfg.Tenant.Field; field != "" && !schema.FieldNamePattern.MatchString(field) {
+		return fmt.Errorf("tenant.field 字段名不合法: %q", field)
+	}
+	return nil
+}
+
+// tenantField 文档中的租户字段
+func (st *handlerState) tenantField() string {
+	if field := st.config.Tenant.Field; field != "" {
+		return field
+	}
+	return defaultTenantField
+}
+
+// tenantFilter 多租户模式下返回当前调用方必须附加的过滤条件
+// 不属于任何租户的 admin 不受限制，其他调用方缺少租户时返回 errTenantRequired
+func (st *handlerState) tenantFilter(c *gin.Context) (string, error) {
+	if !st.config.Tenant.Enabled {
+		return "", nil
+	}
+
+	principal, ok := middleware.PrincipalFrom(c)
+	if !ok {
+		return "", errTenantRequired
+	}
+	if principal.Tenant != "" {
+		return st.tenantCondition(principal.Tenant)
+	}
+	if principal.HasScope(models.ScopeAdmin) {
+		return "", nil
+	}
+	return "", errTenantRequired
+}
+
+// checkTenantWrite 多租户模式下属于某个租户的调用方不能写入文档或按 ID 删除文档
+// 主键相同的文档会被直接覆盖，不读取原文档就无法确认它属于同一租户
+func (st *handlerState) checkTenantWrite(c *gin.Context) error {
+	tenantFilter, err := st.tenantFilter(c)
+	if err != nil {
+		return err
+	}
+	if tenantFilter != "" {
+		return errTenantDocumentWrite
+	}
+	return nil
+}
+
+// checkIndexWide 多租户模式下只有不受租户限制的调用方可以访问索引级的接口
+// 设置、任务、缓存、配置和索引统计覆盖所有租户的数据，无法按租户过滤
+func (st *handlerState) checkIndexWide(c *gin.Context) error {
+	tenantFilter, err := st.tenantFilter(c)
+	if err != nil {
+		return err
+	}
+	if tenantFilter != "" {
+		return errTenantIndexWide
+	}
+	return nil
+}
+
+// RequireIndexWide 拒绝属于租户的调用方访问索引级的接口，放在认证和鉴权之后
+func (h *SearchHandler) RequireIndexWide() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := h.state().checkIndexWide(c); err != nil {
+			c.AbortWithStatusJSON(tenantErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.Next()
+	}
+}
+
+// tenantCondition 生成租户过滤条件，租户值同样经过转义
+func (st *handlerState) tenantCondition(tenant string) (string, error) {
+	value, err := quoteFilterString(tenant)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q", errTenantInvalid, tenant)
+	}
+	return st.tenantField() + " = " + value, nil
+}
+
+// andFilters 用 AND 合并租户过滤条件和用户过滤条件
+func andFilters(tenant, filter string) string {
+	switch {
+	case tenant == "":
+		return filter
+	case filter == "":
+		return tenant
+	default:
+		return tenant + " AND (" + filter + ")"
+	}
+}
+
+// tenantErrorStatus 缺少租户、租户调用方写入文档或访问索引级接口返回 403
+func tenantErrorStatus(err error) int {
+	if errors.Is(err, errTenantRequired) || errors.Is(err, errTenantDocumentWrite) || errors.Is(err, errTenantInvalid) || errors.Is(err, errTenantIndexWide) {
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// CreateTenantToken 为当前租户签发 Meilisearch 租户令牌
+// 令牌的搜索规则对所有允许访问的索引附加租户过滤条件，客户端可以直接请求 Meilisearch 搜索
+func (h *SearchHandler) CreateTenantToken(c *gin.Context) {
+	st := h.state()
+	if !st.config.Tenant.Enabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未启用多租户模式"})
+		return
+	}
+	tokenConfig := st.config.Tenant.Token
+	if tokenConfig.APIKeyUID == "" || tokenConfig.APIKey == "" {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "未配置 tenant.token，无法签发租户令牌"})
+		return
+	}
+
+	var req models.TenantTokenRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误: " + err.Error()})
+			return
+		}
+	}
+
+	// 租户调用方只能为自己签发，不属于任何租户的 admin 可以指定租户
+	principal, _ := middleware.PrincipalFrom(c)
+	tenant := ""
+	if principal != nil {
+		tenant = principal.Tenant
+	}
+	if req.Tenant != "" && req.Tenant != tenant {
+		if tenant != "" || principal == nil || !principal.HasScope(models.ScopeAdmin) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "只能为自己所属的租户签发令牌"})
+			return
+		}
+		tenant = req.Tenant
+	}
+	if tenant == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": errTenantRequired.Error()})
+		return
+	}
+
+	ttl := tokenConfig.TTL
+	if ttl <= 0 {
+		ttl = defaultTenantTokenTTL
+	}
+	if req.ExpiresIn < 0 || req.ExpiresIn > ttl {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("expires_in 必须在 1 到 %d 之间", ttl)})
+		return
+	}
+	if req.ExpiresIn > 0 {
+		ttl = req.ExpiresIn
+	}
+	expiresAt := time.Now().Add(time.Duration(ttl) * time.Second).UTC()
+
+	uids := make([]string, 0, len(st.indexes))
+	for uid := range st.indexes {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+
+	condition, err := st.tenantCondition(tenant)
+	if err != nil {
+		c.JSON(tenantErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	rules := make(map[string]interface{}, len(uids))
+	for _, uid := range uids {
+		rules[uid] = map[string]interface{}{"filter": condition}
+	}
+
+	token, err := st.client.GenerateTenantToken(tokenConfig.APIKeyUID, rules, &meilisearch.TenantTokenOptions{
+		APIKey:    tokenConfig.APIKey,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		logError(c, "签发租户令牌错误", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "签发租户令牌失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TenantTokenResponse{
+		Token:     token,
+		Tenant:    tenant,
+		IndexUIDs: uids,
+		ExpiresAt: expiresAt,
+	})
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"meili_dog/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tenantConfig 启用多租户的配置
+func tenantConfig(t *testing.T, meili *fakeMeili) models.AppConfig {
+	t.Helper()
+	cfg := testConfig(t, meili)
+	cfg.Tenant.Enabled = true
+	cfg.Auth = models.AuthConfig{Providers: []string{"api_key"}, APIKeys: testKeys}
+	return cfg
+}
+
+func TestTenantFilterInjection(t *testing.T) {
+	meili := newFakeMeili(t)
+	meili.reply("POST /indexes/users/search", http.StatusOK, `{"hits":[],"estimatedTotalHits":0,"processingTimeMs":1}`)
+	meili.reply("POST /multi-search", http.StatusOK, `{"results":[{"indexUid":"users","hits":[],"estimatedTotalHits":0}]}`)
+	meili.reply("GET /indexes/users/settings/filterable-attributes", http.StatusOK, `["genre","tenant_id"]`)
+	meili.reply("POST /indexes/users/documents/delete", http.StatusAccepted, `{"taskUid":1,"indexUid":"users","status":"enqueued","type":"documentDeletion"}`)
+
+	h := NewSearchHandler(tenantConfig(t, meili))
+	router := testRouter(t, func(r gin.IRoutes) {
+		r.POST("/search", h.SearchPost)
+		r.POST("/multi-search", h.MultiSearch)
+		r.POST("/documents", h.AddDocuments)
+		r.DELETE("/documents", h.DeleteDocuments)
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		key        string
+		body       string
+		route      string // 期望收到租户过滤条件的 Meilisearch 接口
+		wantStatus int
+		wantFilter string
+	}{
+		{
+			name: "租户搜索", method: http.MethodPost, target: "/search", key: "tenant-key",
+			body:  `{"query":"a"}`,
+			route: "POST /indexes/users/search", wantStatus: http.StatusOK, wantFilter: `tenant_id = "a"`,
+		},
+		{
+			name: "与用户过滤条件合并", method: http.MethodPost, target: "/search", key: "tenant-key",
+			body:  `{"query":"a","filter":{"or":[{"field":"genre","op":"eq","value":"x"},{"field":"tenant_id","op":"eq","value":"b"}]}}`,
+			route: "POST /indexes/users/search", wantStatus: http.StatusOK, wantFilter: `tenant_id = "a" AND ((genre = "x" OR tenant_id = "b"))`,
+		},
+		{
+			name: "租户值转义", method: http.MethodPost, target: "/search", key: "quote-key",
+			body:  `{"query":"a"}`,
+			route: "POST /indexes/users/search", wantStatus: http.StatusOK, wantFilter: `tenant_id = "a\"x"`,
+		},
+		{
+			name: "多索引搜索", method: http.MethodPost, target: "/multi-search", key: "tenant-key",
+			body:  `{"queries":[{"index_uid":"users","query":"a"}]}`,
+			route: "POST /multi-search", wantStatus: http.StatusOK, wantFilter: `tenant_id = "a"`,
+		},
+		{
+			name: "租户按过滤条件删除", method: http.MethodDelete, target: "/documents", key: "tenant-admin-key",
+			body:  `{"filter":{"field":"genre","op":"eq","value":"x"}}`,
+			route: "POST /indexes/users/documents/delete", wantStatus: http.StatusOK, wantFilter: `tenant_id = "a" AND (genre = "x")`,
+		},
+		{
+			name: "不属于租户的 admin 不受限制", method: http.MethodPost, target: "/search", key: "admin-key",
+			body:  `{"query":"a"}`,
+			route: "POST /indexes/users/search", wantStatus: http.StatusOK, wantFilter: "",
+		},
+		{
+			name: "没有租户的搜索调用方", method: http.MethodPost, target: "/search", key: "search-key",
+			body: `{"query":"a"}`, wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "租户按 ID 删除", method: http.MethodDelete, target: "/documents", key: "tenant-admin-key",
+			body: `{"ids":[1]}`, wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "租户写入文档", method: http.MethodPost, target: "/documents", key: "tenant-admin-key",
+			body: `[{"id":1,"tenant_id":"a"}]`, wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := 0
+			if tt.route != "" {
+				before = len(meili.received(tt.route))
+			}
+			w := do(router, tt.method, tt.target, tt.key, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("状态码 = %d，期望 %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.route == "" {
+				return
+			}
+
+			requests := meili.received(tt.route)
+			if len(requests) != before+1 {
+				t.Fatalf("%s 收到 %d 次请求，期望 1 次", tt.route, len(requests)-before)
+			}
+			if got := sentFilter(t, requests[len(requests)-1].Body); got != tt.wantFilter {
+				t.Errorf("过滤条件 = %q，期望 %q", got, tt.wantFilter)
+			}
+		})
+	}
+}
+
+// sentFilter 取出发送给 Meilisearch 的过滤条件，多索引搜索取第一个查询
+func sentFilter(t *testing.T, body string) string {
+	t.Helper()
+	var sent struct {
+		Filter  string `json:"filter"`
+		Queries []struct {
+			Filter string `json:"filter"`
+		} `json:"queries"`
+	}
+	if err := json.Unmarshal([]byte(body), &sent); err != nil {
+		t.Fatalf("解析 %s: %v", body, err)
+	}
+	if len(sent.Queries) > 0 {
+		return sent.Queries[0].Filter
+	}
+	return sent.Filter
+}
+
+func TestRequireIndexWide(t *testing.T) {
+	meili := newFakeMeili(t)
+	h := NewSearchHandler(tenantConfig(t, meili))
+	router := testRouter(t, func(r gin.IRoutes) {
+		r.GET("/index-wide", h.RequireIndexWide(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	})
+
+	tests := []struct {
+		key        string
+		wantStatus int
+	}{
+		{key: "admin-key", wantStatus: http.StatusOK},
+		{key: "tenant-admin-key", wantStatus: http.StatusForbidden},
+		{key: "tenant-key", wantStatus: http.StatusForbidden},
+		{key: "search-key", wantStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if w := do(router, http.MethodGet, "/index-wide", tt.key, ""); w.Code != tt.wantStatus {
+			t.Errorf("%s: 状态码 = %d，期望 %d", tt.key, w.Code, tt.wantStatus)
+		}
+	}
+
+	// 未启用多租户时不做限制
+	cfg := testConfig(t, meili)
+	if _, err := h.Reload(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if w := do(router, http.MethodGet, "/index-wide", "tenant-admin-key", ""); w.Code != http.StatusOK {
+		t.Errorf("未启用多租户: 状态码 = %d，期望 200", w.Code)
+	}
+}
+
+// TestReloadTenantUsesRunningAuth 热加载按启动时的认证配置校验多租户
+func TestReloadTenantUsesRunningAuth(t *testing.T) {
+	meili := newFakeMeili(t)
+
+	// 启动时启用了认证，新配置文件删除 auth 不影响正在使用的认证
+	h := NewSearchHandler(tenantConfig(t, meili))
+	next := tenantConfig(t, meili)
+	next.Auth = models.AuthConfig{}
+	if _, err := h.Reload(next); err != nil {
+		t.Errorf("Reload() = %v，期望按正在使用的认证配置通过校验", err)
+	}
+
+	// 启动时没有认证，新配置文件添加 auth 需要重启才能生效，不能启用多租户
+	h = NewSearchHandler(testConfig(t, meili))
+	if _, err := h.Reload(tenantConfig(t, meili)); err == nil {
+		t.Error("Reload() 成功，期望因为正在使用的配置没有启用认证而失败")
+	}
+}
@@ -1,61 +1,208 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
 	"strconv"
+	"syscall"
+	"time"
 
 	"meili_dog/config"
 	"meili_dog/handlers"
+	"meili_dog/logging"
+	"meili_dog/metrics"
+	"meili_dog/middleware"
 	"meili_dog/models"
+	"meili_dog/tracing"
 
 	"github.com/gin-gonic/gin"
 )
 
+// shutdownTimeout 关闭服务器时等待处理中的请求完成的最长时间
+const shutdownTimeout = 10 * time.Second
+
 func main() {
+	// config 子命令在加载配置之前执行，以便报告配置错误
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfig(os.Args[2:]))
+	}
+
 	// 加载配置
-	cfg, err := loadConfig()
+	cfg, err := config.LoadConfig(configPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载配置失败: %v\n", err)
+		os.Exit(1)
+	}
+	if err := logging.Setup(cfg.Log); err != nil {
+		fatal("日志配置错误", "error", err)
+	}
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
 	if err != nil {
-		log.Fatalf("加载配置失败: %v", err)
+		fatal("链路追踪配置错误", "error", err)
 	}
 
-	// 检查索引配置
-	if cfg.Search.IndexUID == "" {
-		log.Fatal("配置文件中未设置索引UID (search.index_uid)")
+	// 命令行子命令
+	if len(os.Args) > 1 {
+		code := runCommand(cfg, os.Args[1:])
+		shutdownTracing(context.Background())
+		os.Exit(code)
 	}
 
-	log.Printf("配置索引: %s", cfg.Search.IndexUID)
+	if cfg.Search.IndexUID != "" {
+		slog.Info("配置索引", "index", cfg.Search.IndexUID)
+	}
+	for _, idx := range cfg.Indexes {
+		slog.Info("配置索引", "index", idx.UID)
+	}
 
 	// 初始化搜索处理器
 	searchHandler := handlers.NewSearchHandler(*cfg)
 
+	// 同步配置中 apply_on_startup = true 的索引设置
+	if results, err := searchHandler.SyncSettings(context.Background(), false, true); err != nil {
+		slog.Error("同步索引设置失败", "error", err)
+		for _, result := range results {
+			if result.Error != "" {
+				slog.Error("同步索引设置失败", "index", result.IndexUID, "error", result.Error)
+			}
+		}
+	}
+
 	// 设置Gin模式
 	if os.Getenv("GIN_MODE") == "release" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	// 创建路由
-	router := gin.Default()
+	// 创建路由，请求 ID 放在最前面，后续中间件的日志都带上请求 ID
+	router := gin.New()
+	router.Use(middleware.RequestID())
+	if cfg.Tracing.Enabled {
+		router.Use(middleware.Tracing())
+	}
+	router.Use(middleware.AccessLog())
+
+	// Prometheus 指标，放在 Recovery 之前，panic 的请求同样按 500 计数
+	if !cfg.Metrics.Disabled {
+		router.Use(middleware.Metrics())
+		if searchHandler.SearchCacheStats().Enabled {
+			metrics.RegisterSearchCache(searchHandler.SearchCacheStats)
+		}
+	}
+	router.Use(middleware.Recovery())
 
-	// 添加CORS中间件（可选）
-	router.Use(corsMiddleware())
+	// 只信任配置的代理转发的客户端 IP，防止伪造 X-Forwarded-For 绕过按 IP 限流
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		fatal("server.trusted_proxies 配置错误", "error", err)
+	}
+
+	// 跨域中间件，预检请求按目标接口所在路由组的策略处理
+	cors, err := middleware.NewCORS(cfg.CORS)
+	if err != nil {
+		fatal("CORS 配置错误", "error", err)
+	}
+	router.Use(cors.Preflight(router))
+
+	// 认证中间件
+	auth, err := middleware.NewAuth(cfg.Auth)
+	if err != nil {
+		fatal("初始化认证失败", "error", err)
+	}
+	if err := handlers.ValidateTenantConfig(*cfg); err != nil {
+		fatal("多租户配置错误", "error", err)
+	}
+	if !auth.Enabled() {
+		slog.Warn("未配置 auth.providers，所有接口均无需认证")
+	}
+
+	// 限流中间件，放在认证之后以便按 API 密钥或租户限流
+	limiter, err := middleware.NewRateLimiter(cfg.RateLimit)
+	if err != nil {
+		fatal("限流配置错误", "error", err)
+	}
+
+	authenticate := auth.Authenticate()
+	requireSearch := auth.Require(models.ScopeSearch)
+	requireAdmin := auth.Require(models.ScopeAdmin)
+
+	// 按 IP 限制认证失败，凭证无效的请求在认证中间件中直接返回 401，需要放在认证之前
+	limitAuth := func(c *gin.Context) { c.Next() }
+	if auth.Enabled() {
+		limitAuth = limiter.LimitAuth()
+	}
+
+	// 路由组中间件，先应用跨域策略，再认证、限流和鉴权
+	searchChain := []gin.HandlerFunc{
+		cors.Policy(models.RouteGroupSearch), limitAuth, authenticate, limiter.Limit(models.RouteGroupSearch), requireSearch,
+	}
+	adminChain := []gin.HandlerFunc{
+		cors.Policy(models.RouteGroupAdmin), limitAuth, authenticate, limiter.Limit(models.RouteGroupAdmin), requireAdmin,
+	}
+
+	// 设置、任务、缓存、配置、指标和索引统计覆盖所有租户，属于租户的调用方不能访问
+	indexWide := searchHandler.RequireIndexWide()
+
+	if !cfg.Metrics.Disabled {
+		serveMetrics(router, cfg, append(slices.Clip(adminChain), indexWide))
+	}
 
 	// 路由定义
 	api := router.Group("/api/v1")
 	{
-		api.GET("/health", searchHandler.HealthCheck)
-		api.GET("/index", searchHandler.GetIndexInfo) // 改为单数，获取当前索引信息
-		api.GET("/search", searchHandler.Search)
+		public := api.Group("", cors.Policy(models.RouteGroupPublic), limiter.Limit(models.RouteGroupPublic))
+		{
+			public.GET("/health", searchHandler.HealthCheck)
+		}
 
-		settings := api.Group("/settings")
+		// 搜索类接口，需要 search 权限，public_search = true 时允许匿名访问
+		search := api.Group("", searchChain...)
 		{
-			settings.GET("/", searchHandler.GetSettings)                                     // 获取所有设置
-			settings.PUT("/searchable-attributes", searchHandler.UpdateSearchableAttributes) // 设置可搜索字段
-			settings.PUT("/filterable-attributes", searchHandler.UpdateFilterableAttributes) // 设置可过滤字段
-			settings.PUT("/sortable-attributes", searchHandler.UpdateSortableAttributes)     // 设置可排序字段
-			settings.PUT("/ranking-rules", searchHandler.UpdateRankingRules)                 // 更新排序规则
-			settings.POST("/reset", searchHandler.ResetSettings)                             // 重置所有设置
+			search.GET("/index", indexWide, searchHandler.GetIndexInfo) // 改为单数，获取当前索引信息
+			search.GET("/schema", searchHandler.GetSchema)
+			search.GET("/search", searchHandler.Search)
+			search.POST("/search", searchHandler.SearchPost)
+			search.GET("/facets/:facet/search", searchHandler.SearchFacetValues)
+			search.POST("/multi-search", searchHandler.MultiSearch)
+			search.GET("/indexes", searchHandler.ListIndexes)
+			search.POST("/tenant-token", searchHandler.CreateTenantToken)
+		}
+
+		// 管理接口，需要 admin 权限
+		admin := api.Group("", adminChain...)
+		{
+			registerDocumentRoutes(admin.Group("/documents"), searchHandler)
+			admin.GET("/tasks", indexWide, searchHandler.ListTasks)
+			admin.GET("/tasks/:task_uid", indexWide, searchHandler.GetTask)
+			admin.GET("/cache", indexWide, searchHandler.GetSearchCache)
+			admin.DELETE("/cache", indexWide, searchHandler.PurgeSearchCache)
+			admin.GET("/admin/config", indexWide, searchHandler.GetConfig)
+			admin.GET("/export", searchHandler.ExportDocuments)
+			registerSettingsRoutes(admin.Group("/settings", indexWide), searchHandler)
+		}
+
+		// 多索引路由，:uid 必须在配置的索引列表中
+		indexes := api.Group("/indexes/:uid")
+		{
+			indexSearch := indexes.Group("", searchChain...)
+			{
+				indexSearch.GET("", indexWide, searchHandler.GetIndexInfo)
+				indexSearch.GET("/schema", searchHandler.GetSchema)
+				indexSearch.GET("/search", searchHandler.Search)
+				indexSearch.POST("/search", searchHandler.SearchPost)
+				indexSearch.GET("/facets/:facet/search", searchHandler.SearchFacetValues)
+			}
+			indexAdmin := indexes.Group("", adminChain...)
+			{
+				registerSettingsRoutes(indexAdmin.Group("/settings", indexWide), searchHandler)
+				registerDocumentRoutes(indexAdmin.Group("/documents"), searchHandler)
+				indexAdmin.GET("/export", searchHandler.ExportDocuments)
+			}
 		}
 	}
 
@@ -65,47 +212,101 @@ func main() {
 		port = strconv.FormatInt(cfg.Server.LocalPort, 10)
 	}
 
-	log.Printf("服务器启动在端口 %s", port)
-	log.Printf("MeiliSearch 地址: %s", cfg.Server.Address)
-	log.Printf("目标索引: %s", cfg.Search.IndexUID)
+	slog.Info("服务器启动", "port", port, "meilisearch", cfg.Server.Address, "default_index", searchHandler.DefaultIndexUID())
+
+	// 配置热加载
+	watchConfig(configPath(), cfg, searchHandler)
+
+	server := &http.Server{Addr: ":" + port, Handler: router}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal("启动服务器失败", "error", err)
+		}
+	}()
+
+	// 收到退出信号后等待处理中的请求完成，并发送尚未导出的 span
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	slog.Info("服务器正在关闭")
 
-	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("启动服务器失败: %v", err)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("关闭服务器失败", "error", err)
 	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("关闭链路追踪失败", "error", err)
+	}
+}
+
+// fatal 记录错误日志后退出
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
 
-// loadConfig 加载配置
-func loadConfig() (*models.AppConfig, error) {
-	// 获取配置文件路径
-	configPath := os.Getenv("CONFIG_PATH")
-	if configPath == "" {
-		configPath = "config/config.toml"
+// serveMetrics 注册指标接口，配置 metrics.listen 时在单独的端口上提供
+// 与 API 使用同一端口时经过管理接口的认证和鉴权，避免指标暴露给匿名调用方
+func serveMetrics(router *gin.Engine, cfg *models.AppConfig, adminChain []gin.HandlerFunc) {
+	path := cfg.Metrics.Path
+	if path == "" {
+		path = "/metrics"
 	}
 
-	// 确保路径是绝对路径
-	if !filepath.IsAbs(configPath) {
-		wd, err := os.Getwd()
-		if err != nil {
-			return nil, err
-		}
-		configPath = filepath.Join(wd, configPath)
+	if cfg.Metrics.Listen == "" {
+		chain := append(slices.Clip(adminChain), gin.WrapH(metrics.Handler()))
+		router.GET(path, chain...)
+		return
 	}
 
-	return config.LoadConfig(configPath)
+	mux := http.NewServeMux()
+	mux.Handle(path, metrics.Handler())
+	go func() {
+		slog.Info("指标服务启动", "listen", cfg.Metrics.Listen, "path", path)
+		if err := http.ListenAndServe(cfg.Metrics.Listen, mux); err != nil {
+			fatal("启动指标服务失败", "error", err)
+		}
+	}()
 }
 
-// corsMiddleware CORS中间件
-func corsMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+// registerSettingsRoutes 注册设置管理路由
+func registerSettingsRoutes(settings *gin.RouterGroup, searchHandler *handlers.SearchHandler) {
+	settings.Use(handlers.TaskWaitParams())
+	settings.GET("/", searchHandler.GetSettings)                                     // 获取所有设置
+	settings.PATCH("", searchHandler.PatchSettings)                                  // 部分更新任意设置
+	settings.PUT("/searchable-attributes", searchHandler.UpdateSearchableAttributes) // 设置可搜索字段
+	settings.PUT("/filterable-attributes", searchHandler.UpdateFilterableAttributes) // 设置可过滤字段
+	settings.PUT("/sortable-attributes", searchHandler.UpdateSortableAttributes)     // 设置可排序字段
+	settings.PUT("/ranking-rules", searchHandler.UpdateRankingRules)                 // 更新排序规则
+	settings.POST("/reset", searchHandler.ResetSettings)                             // 重置所有设置
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
+	// 设置快照，每次修改设置前自动保存
+	settings.GET("/snapshots", searchHandler.ListSnapshots)                     // 快照列表
+	settings.GET("/snapshots/diff", searchHandler.DiffSnapshots)                // 比较快照
+	settings.GET("/snapshots/:version", searchHandler.ExportSnapshot)           // 导出快照
+	settings.POST("/snapshots/:version/restore", searchHandler.RestoreSnapshot) // 恢复快照
+}
+
+// registerDocumentRoutes 注册文档写入路由
+func registerDocumentRoutes(documents *gin.RouterGroup, searchHandler *handlers.SearchHandler) {
+	documents.Use(handlers.TaskWaitParams())
+	documents.POST("", searchHandler.AddDocuments)                 // 添加或替换文档
+	documents.PUT("", searchHandler.UpdateDocuments)               // 部分更新文档
+	documents.DELETE("", searchHandler.DeleteDocuments)            // 按ID或过滤条件删除文档
+	documents.POST("/import", searchHandler.ImportDocumentsUpload) // 批量导入文件
+}
 
-		c.Next()
+// configPath 配置文件路径，CONFIG_PATH 为空时使用 config/config.toml
+func configPath() string {
+	path := os.Getenv("CONFIG_PATH")
+	if path == "" {
+		path = "config/config.toml"
+	}
+
+	// 确保路径是绝对路径
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
 	}
+	return path
 }
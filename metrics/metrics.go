@@ -0,0 +1,151 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"meili_dog/models"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "meili_dog"
+
+// registry 独立的指标注册表，只包含 meili_dog 指标和 Go 运行时指标
+var registry = prometheus.NewRegistry()
+
+var (
+	// HTTP 请求
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "按路由、方法和状态码统计的请求数",
+	}, []string{"route", "method", "status"})
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "按路由和方法统计的请求耗时",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+	httpInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "正在处理的请求数",
+	})
+
+	// Meilisearch 上游请求
+	upstreamDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_request_duration_seconds",
+		Help:      "请求 Meilisearch 的耗时",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+	upstreamErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_errors_total",
+		Help:      "请求 Meilisearch 失败的次数",
+	}, []string{"operation"})
+
+	// 搜索结果
+	searchProcessing = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "search_processing_time_seconds",
+		Help:      "Meilisearch 返回的 processingTimeMs 分布",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 13), // 1ms 到约 4s
+	}, []string{"index"})
+	searches = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "searches_total",
+		Help:      "执行的搜索次数，包括命中缓存的搜索",
+	}, []string{"index"})
+	zeroResultSearches = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "search_zero_results_total",
+		Help:      "没有结果的搜索次数",
+	}, []string{"index"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration, httpInFlight,
+		upstreamDuration, upstreamErrors,
+		searchProcessing, searches, zeroResultSearches,
+	)
+}
+
+// Handler 返回 /metrics 处理器
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
+
+// ObserveRequest 记录一次 HTTP 请求
+func ObserveRequest(route, method, status string, duration time.Duration) {
+	httpRequests.WithLabelValues(route, method, status).Inc()
+	httpDuration.WithLabelValues(route, method).Observe(duration.Seconds())
+}
+
+// RequestStarted 请求开始处理，返回的函数在请求结束时调用
+func RequestStarted() func() {
+	httpInFlight.Inc()
+	return httpInFlight.Dec
+}
+
+// ObserveUpstream 记录一次 Meilisearch 请求，err 不为空时计入错误数
+func ObserveUpstream(operation string, duration time.Duration, err error) {
+	upstreamDuration.WithLabelValues(operation).Observe(duration.Seconds())
+	if err != nil {
+		upstreamErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+// ObserveSearch 记录一次搜索结果，cached 为 true 时不重复记录 processingTimeMs
+func ObserveSearch(indexUID string, processingTimeMs int64, totalHits int64, cached bool) {
+	searches.WithLabelValues(indexUID).Inc()
+	if totalHits == 0 {
+		zeroResultSearches.WithLabelValues(indexUID).Inc()
+	}
+	if !cached {
+		searchProcessing.WithLabelValues(indexUID).Observe(float64(processingTimeMs) / 1000)
+	}
+}
+
+// RegisterSearchCache 导出搜索缓存的统计，stats 在每次抓取时调用
+func RegisterSearchCache(stats func() models.SearchCacheStats) {
+	registry.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "search_cache_hits_total",
+			Help:      "搜索缓存命中次数",
+		}, func() float64 { return float64(stats().Hits) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "search_cache_misses_total",
+			Help:      "搜索缓存未命中次数",
+		}, func() float64 { return float64(stats().Misses) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "search_cache_evictions_total",
+			Help:      "搜索缓存因容量淘汰的条目数",
+		}, func() float64 { return float64(stats().Evictions) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "search_cache_entries",
+			Help:      "搜索缓存的条目数",
+		}, func() float64 { return float64(stats().Entries) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "search_cache_hit_ratio",
+			Help:      "启动以来的搜索缓存命中率",
+		}, func() float64 {
+			s := stats()
+			if s.Hits+s.Misses == 0 {
+				return 0
+			}
+			return float64(s.Hits) / float64(s.Hits+s.Misses)
+		}),
+	)
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+
+	"meili_dog/models"
+)
+
+// APIKeyHeader 静态 API 密钥请求头
+const APIKeyHeader = "X-API-Key"
+
+// apiKeyAuthenticator 静态 API 密钥认证
+type apiKeyAuthenticator struct {
+	keys []apiKey
+}
+
+type apiKey struct {
+	name   string
+	digest [sha256.Size]byte
+	scopes []string
+	tenant string
+}
+
+func newAPIKeyAuthenticator(configs []models.APIKeyConfig) (*apiKeyAuthenticator, error) {
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("未配置 auth.api_keys")
+	}
+
+	authenticator := &apiKeyAuthenticator{}
+	for i, cfg := range configs {
+		if cfg.Key == "" {
+			return nil, fmt.Errorf("第 %d 个 API 密钥为空", i+1)
+		}
+		if err := validateScopes(cfg.Scopes); err != nil {
+			return nil, fmt.Errorf("API 密钥 %s: %w", cfg.Name, err)
+		}
+		name := cfg.Name
+		if name == "" {
+			name = fmt.Sprintf("api_key_%d", i+1)
+		}
+		authenticator.keys = append(authenticator.keys, apiKey{
+			name:   name,
+			digest: sha256.Sum256([]byte(cfg.Key)),
+			scopes: cfg.Scopes,
+			tenant: cfg.Tenant,
+		})
+	}
+	return authenticator, nil
+}
+
+func (a *apiKeyAuthenticator) Name() string {
+	return "api_key"
+}
+
+// Authenticate 比较密钥的摘要，避免逐字节比较泄露密钥长度和内容
+func (a *apiKeyAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		return nil, errNoCredentials
+	}
+
+	digest := sha256.Sum256([]byte(key))
+	for _, k := range a.keys {
+		if subtle.ConstantTimeCompare(digest[:], k.digest[:]) == 1 {
+			return &Principal{Subject: k.name, Scopes: k.scopes, Tenant: k.tenant}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: API 密钥无效", errInvalidCredentials)
+}
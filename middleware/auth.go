@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"meili_dog/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// principalKey 上下文中保存认证主体的键
+const principalKey = "meili_dog.principal"
+
+var (
+	// errNoCredentials 请求没有携带该认证方式的凭证，继续尝试下一种认证方式
+	errNoCredentials = errors.New("未提供认证凭证")
+	// errInvalidCredentials 凭证存在但校验失败
+	errInvalidCredentials = errors.New("认证失败")
+)
+
+// Principal 认证通过的调用方
+type Principal struct {
+	Subject  string                 // API 密钥名称、HMAC 密钥 ID 或 JWT 的 sub
+	Provider string                 // 认证方式
+	Scopes   []string               // 权限范围
+	Tenant   string                 // 所属租户，为空表示不属于任何租户
+	Claims   map[string]interface{} // JWT 声明，其他认证方式为空
+}
+
+// HasScope 判断是否具备指定权限范围，admin 包含所有权限
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == models.ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator 一种认证方式
+// 请求没有携带该方式的凭证时返回 errNoCredentials，凭证无效时返回 errInvalidCredentials
+type Authenticator interface {
+	Name() string
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// Auth 按配置组合的认证中间件
+type Auth struct {
+	authenticators []Authenticator
+	publicSearch   bool
+}
+
+// NewAuth 根据配置创建认证中间件，providers 为空时不启用认证
+func NewAuth(cfg models.AuthConfig) (*Auth, error) {
+	auth := &Auth{publicSearch: cfg.PublicSearch}
+
+	for _, provider := range cfg.Providers {
+		var (
+			authenticator Authenticator
+			err           error
+		)
+		switch provider {
+		case "api_key":
+			authenticator, err = newAPIKeyAuthenticator(cfg.APIKeys)
+		case "hmac":
+			authenticator, err = newHMACAuthenticator(cfg.HMAC)
+		case "jwt":
+			authenticator, err = newJWTAuthenticator(cfg.JWT)
+		default:
+			err = fmt.Errorf("未知的认证方式: %q，可选 api_key、hmac、jwt", provider)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("初始化认证方式 %s 失败: %w", provider, err)
+		}
+		auth.authenticators = append(auth.authenticators, authenticator)
+	}
+	return auth, nil
+}
+
+// Enabled 是否启用了认证
+func (a *Auth) Enabled() bool {
+	return len(a.authenticators) > 0
+}
+
+// Authenticate 依次尝试各认证方式，成功后把调用方写入上下文
+// 没有携带凭证的请求继续执行，由 Require 决定是否拒绝；携带的凭证无效时直接返回 401
+func (a *Auth) Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, authenticator := range a.authenticators {
+			principal, err := authenticator.Authenticate(c.Request)
+			if errors.Is(err, errNoCredentials) {
+				continue
+			}
+			if err != nil {
+				slog.WarnContext(c.Request.Context(), "认证失败", "provider", authenticator.Name(), "error", err)
+				c.AbortWithStatusJSON(authErrorStatus(err), gin.H{"error": err.Error()})
+				return
+			}
+			principal.Provider = authenticator.Name()
+			c.Set(principalKey, principal)
+			break
+		}
+		// 签名请求写入临时文件的请求体在请求结束后删除
+		if body, ok := c.Request.Body.(*spooledBody); ok {
+			defer body.Close()
+		}
+		c.Next()
+	}
+}
+
+// authErrorStatus 认证失败的状态码，请求体不符合要求时返回对应的 4xx，临时文件写入失败返回 500
+func authErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errBodyTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, errStreamingMediaType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, errBodySpool):
+		return http.StatusInternalServerError
+	default:
+		return http.StatusUnauthorized
+	}
+}
+
+// Require 要求调用方具备指定权限范围，未启用认证时不做限制
+// 搜索权限在配置 public_search = true 时对匿名请求开放
+func (a *Auth) Require(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !a.Enabled() || (scope == models.ScopeSearch && a.publicSearch) {
+			c.Next()
+			return
+		}
+
+		principal, ok := PrincipalFrom(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "需要认证"})
+			return
+		}
+		if !principal.HasScope(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "权限不足，需要 " + scope + " 权限"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// PrincipalFrom 获取当前请求的调用方，未认证时返回 false
+func PrincipalFrom(c *gin.Context) (*Principal, bool) {
+	value, ok := c.Get(principalKey)
+	if !ok {
+		return nil, false
+	}
+	principal, ok := value.(*Principal)
+	return principal, ok
+}
+
+// validateScopes 校验配置中的权限范围
+func validateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("scopes 不能为空")
+	}
+	for _, scope := range scopes {
+		if scope != models.ScopeAdmin && scope != models.ScopeSearch {
+			return fmt.Errorf("未知的权限范围: %q，可选 admin、search", scope)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,341 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"meili_dog/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// testJWTKey 测试用的 RSA 私钥，公钥写入临时 JWKS 文件
+func testJWTKey(t *testing.T) (*rsa.PrivateKey, string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encode := base64.RawURLEncoding.EncodeToString
+	jwks := fmt.Sprintf(`{"keys":[{"kty":"RSA","kid":"k1","n":%q,"e":%q}]}`,
+		encode(key.N.Bytes()), encode(big.NewInt(int64(key.E)).Bytes()))
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, []byte(jwks), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return key, path
+}
+
+func signJWT(t *testing.T, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "k1"
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// signHMAC 按签名规则设置请求头，digestBody 为写入 X-Content-SHA256 的内容
+func signHMAC(r *http.Request, keyID, secret string, timestamp time.Time, nonce, digestBody string) {
+	sum := sha256.Sum256([]byte(digestBody))
+	digest := hex.EncodeToString(sum[:])
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n%s", r.Method, r.URL.RequestURI(), ts, nonce, digest)
+
+	r.Header.Set(HMACKeyHeader, keyID)
+	r.Header.Set(HMACTimestampHeader, ts)
+	r.Header.Set(HMACNonceHeader, nonce)
+	r.Header.Set(HMACContentHashHeader, digest)
+	r.Header.Set(HMACSignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+}
+
+func TestAuthenticate(t *testing.T) {
+	jwtKey, jwksFile := testJWTKey(t)
+	auth, err := NewAuth(models.AuthConfig{
+		Providers: []string{"api_key", "hmac", "jwt"},
+		APIKeys: []models.APIKeyConfig{
+			{Name: "frontend", Key: "search-key", Scopes: []string{models.ScopeSearch}},
+			{Name: "shop-a", Key: "tenant-key", Scopes: []string{models.ScopeSearch}, Tenant: "a"},
+		},
+		HMAC: models.HMACConfig{Keys: []models.HMACKeyConfig{
+			{ID: "svc", Secret: "s3cret", Scopes: []string{models.ScopeAdmin}},
+		}},
+		JWT: models.JWTConfig{JWKSFile: jwksFile, Issuer: "me"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 导入接口的请求体写入临时目录，请求结束后应删除
+	tmpDir := t.TempDir()
+	t.Setenv("TMPDIR", tmpDir)
+
+	now := time.Now()
+	body := `{"stop_words":["the"]}`
+	ndjson := "{\"id\":1}\n"
+	tests := []struct {
+		name        string
+		method      string
+		path        string // 默认 /api/v1/settings
+		body        string
+		contentType string
+		prepare     func(r *http.Request)
+		wantStatus  int
+		wantSubject string // 为空表示没有认证主体
+		wantTenant  string
+		wantBody    string // 处理器读到的请求体，为空时不检查
+	}{
+		{
+			name:       "没有凭证",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:        "API 密钥",
+			prepare:     func(r *http.Request) { r.Header.Set(APIKeyHeader, "search-key") },
+			wantStatus:  http.StatusOK,
+			wantSubject: "frontend",
+		},
+		{
+			name:        "带租户的 API 密钥",
+			prepare:     func(r *http.Request) { r.Header.Set(APIKeyHeader, "tenant-key") },
+			wantStatus:  http.StatusOK,
+			wantSubject: "shop-a",
+			wantTenant:  "a",
+		},
+		{
+			name:       "无效的 API 密钥",
+			prepare:    func(r *http.Request) { r.Header.Set(APIKeyHeader, "wrong") },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:        "HMAC 签名",
+			method:      http.MethodPatch,
+			body:        body,
+			prepare:     func(r *http.Request) { signHMAC(r, "svc", "s3cret", now, "nonce-0000000001", body) },
+			wantStatus:  http.StatusOK,
+			wantSubject: "svc",
+		},
+		{
+			name:       "HMAC 重放",
+			method:     http.MethodPatch,
+			body:       body,
+			prepare:    func(r *http.Request) { signHMAC(r, "svc", "s3cret", now, "nonce-0000000001", body) },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "HMAC 请求体被修改",
+			method:     http.MethodPatch,
+			body:       `{"stop_words":["a"]}`,
+			prepare:    func(r *http.Request) { signHMAC(r, "svc", "s3cret", now, "nonce-0000000002", body) },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:        "HMAC 导入接口的流式请求体",
+			method:      http.MethodPost,
+			path:        "/api/v1/documents/import",
+			body:        ndjson,
+			contentType: "application/x-ndjson",
+			prepare:     func(r *http.Request) { signHMAC(r, "svc", "s3cret", now, "nonce-0000000003", ndjson) },
+			wantStatus:  http.StatusOK,
+			wantSubject: "svc",
+			wantBody:    ndjson,
+		},
+		{
+			name:        "HMAC 导入接口的流式请求体被修改",
+			method:      http.MethodPost,
+			path:        "/api/v1/documents/import",
+			body:        "{\"id\":2}\n",
+			contentType: "application/x-ndjson",
+			prepare:     func(r *http.Request) { signHMAC(r, "svc", "s3cret", now, "nonce-0000000006", ndjson) },
+			wantStatus:  http.StatusUnauthorized,
+		},
+		{
+			name:        "HMAC 其他接口不接受流式请求体",
+			method:      http.MethodPatch,
+			body:        "id\n1\n",
+			contentType: "text/csv",
+			prepare:     func(r *http.Request) { signHMAC(r, "svc", "s3cret", now, "nonce-0000000007", "id\n1\n") },
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "HMAC 修改后的 JSON 请求体以 CSV 类型发送",
+			method:      http.MethodPatch,
+			body:        `{"stop_words":["a"]}`,
+			contentType: "text/csv",
+			prepare:     func(r *http.Request) { signHMAC(r, "svc", "s3cret", now, "nonce-0000000008", body) },
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:       "HMAC 签名过期",
+			prepare:    func(r *http.Request) { signHMAC(r, "svc", "s3cret", now.Add(-time.Hour), "nonce-0000000004", "") },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "HMAC 缺少随机串",
+			prepare:    func(r *http.Request) { signHMAC(r, "svc", "s3cret", now, "", "") },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "HMAC 密钥错误",
+			prepare:    func(r *http.Request) { signHMAC(r, "svc", "wrong", now, "nonce-0000000005", "") },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "JWT",
+			prepare: func(r *http.Request) {
+				token := signJWT(t, jwtKey, jwt.MapClaims{"sub": "user-1", "iss": "me", "scope": "search", "tenant_id": "b", "exp": now.Add(time.Hour).Unix()})
+				r.Header.Set("Authorization", "Bearer "+token)
+			},
+			wantStatus:  http.StatusOK,
+			wantSubject: "user-1",
+			wantTenant:  "b",
+		},
+		{
+			name: "JWT 缺少 exp",
+			prepare: func(r *http.Request) {
+				token := signJWT(t, jwtKey, jwt.MapClaims{"sub": "user-1", "iss": "me", "scope": "search"})
+				r.Header.Set("Authorization", "Bearer "+token)
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "JWT 已过期",
+			prepare: func(r *http.Request) {
+				token := signJWT(t, jwtKey, jwt.MapClaims{"sub": "user-1", "iss": "me", "scope": "search", "exp": now.Add(-time.Hour).Unix()})
+				r.Header.Set("Authorization", "Bearer "+token)
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "JWT 签发者不匹配",
+			prepare: func(r *http.Request) {
+				token := signJWT(t, jwtKey, jwt.MapClaims{"sub": "user-1", "iss": "other", "scope": "search", "exp": now.Add(time.Hour).Unix()})
+				r.Header.Set("Authorization", "Bearer "+token)
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			var reqBody io.Reader
+			if tt.body != "" {
+				reqBody = strings.NewReader(tt.body)
+			}
+			path := tt.path
+			if path == "" {
+				path = "/api/v1/settings"
+			}
+			req := httptest.NewRequest(method, path+"?x=1", reqBody)
+			contentType := tt.contentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			req.Header.Set("Content-Type", contentType)
+			if tt.prepare != nil {
+				tt.prepare(req)
+			}
+
+			var (
+				principal *Principal
+				gotBody   []byte
+			)
+			router := gin.New()
+			router.Handle(method, path, auth.Authenticate(), func(c *gin.Context) {
+				principal, _ = PrincipalFrom(c)
+				gotBody, _ = io.ReadAll(c.Request.Body)
+				c.Status(http.StatusOK)
+			})
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("状态码 = %d，期望 %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			switch {
+			case tt.wantSubject == "" && principal != nil:
+				t.Errorf("认证主体 = %q，期望未认证", principal.Subject)
+			case tt.wantSubject != "" && principal == nil:
+				t.Errorf("未认证，期望认证主体 %q", tt.wantSubject)
+			case principal != nil && (principal.Subject != tt.wantSubject || principal.Tenant != tt.wantTenant):
+				t.Errorf("认证主体 = %q（租户 %q），期望 %q（租户 %q）", principal.Subject, principal.Tenant, tt.wantSubject, tt.wantTenant)
+			}
+			if tt.wantBody != "" && string(gotBody) != tt.wantBody {
+				t.Errorf("请求体 = %q，期望 %q", gotBody, tt.wantBody)
+			}
+		})
+	}
+
+	if entries, _ := os.ReadDir(tmpDir); len(entries) != 0 {
+		t.Errorf("请求结束后临时目录中仍有 %d 个文件", len(entries))
+	}
+}
+
+func TestRequire(t *testing.T) {
+	auth, err := NewAuth(models.AuthConfig{
+		Providers: []string{"api_key"},
+		APIKeys: []models.APIKeyConfig{
+			{Name: "frontend", Key: "search-key", Scopes: []string{models.ScopeSearch}},
+			{Name: "ops", Key: "admin-key", Scopes: []string{models.ScopeAdmin}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		key        string
+		scope      string
+		wantStatus int
+	}{
+		{name: "匿名搜索", scope: models.ScopeSearch, wantStatus: http.StatusUnauthorized},
+		{name: "搜索权限", key: "search-key", scope: models.ScopeSearch, wantStatus: http.StatusOK},
+		{name: "搜索权限访问管理接口", key: "search-key", scope: models.ScopeAdmin, wantStatus: http.StatusForbidden},
+		{name: "admin 包含搜索权限", key: "admin-key", scope: models.ScopeSearch, wantStatus: http.StatusOK},
+		{name: "管理权限", key: "admin-key", scope: models.ScopeAdmin, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/", auth.Authenticate(), auth.Require(tt.scope), func(c *gin.Context) { c.Status(http.StatusOK) })
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.key != "" {
+				req.Header.Set(APIKeyHeader, tt.key)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("状态码 = %d，期望 %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"meili_dog/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 内置默认策略
+var (
+	defaultCORSOrigins = []string{"*"}
+	defaultCORSMethods = []string{http.MethodGet, http.MethodPost}
+	adminCORSMethods   = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	defaultCORSHeaders = []string{"Content-Type", "Authorization", APIKeyHeader, HMACKeyHeader, HMACTimestampHeader, HMACNonceHeader, HMACContentHashHeader, HMACSignatureHeader, RequestIDHeader}
+)
+
+const defaultCORSMaxAge = 600
+
+// preflightKey 请求上下文中标记预检请求的键
+type preflightKey struct{}
+
+// CORS 按路由组应用的跨域策略
+type CORS struct {
+	policies map[string]*corsPolicy
+}
+
+type corsPolicy struct {
+	disabled      bool
+	anyOrigin     bool
+	origins       []string // 小写的来源或通配模式
+	methods       map[string]bool
+	allowMethods  string
+	anyHeader     bool
+	allowHeaders  string
+	exposeHeaders string
+	credentials   bool
+	maxAge        string
+}
+
+// NewCORS 根据配置创建跨域中间件，未配置的路由组使用默认策略
+// 管理接口可以修改设置和删除文档，未配置 cors.groups.admin 时禁止跨域访问
+func NewCORS(cfg models.CORSConfig) (*CORS, error) {
+	defaultPolicy, err := newCORSPolicy(cfg.Default, defaultCORSMethods)
+	if err != nil {
+		return nil, fmt.Errorf("cors.default: %w", err)
+	}
+
+	cors := &CORS{policies: map[string]*corsPolicy{
+		models.RouteGroupPublic: defaultPolicy,
+		models.RouteGroupSearch: defaultPolicy,
+		models.RouteGroupAdmin:  {disabled: true},
+	}}
+	for group, policyConfig := range cfg.Groups {
+		if _, ok := cors.policies[group]; !ok {
+			return nil, fmt.Errorf("未知的 CORS 路由组: %q，可选 public、search、admin", group)
+		}
+		methods := defaultCORSMethods
+		if group == models.RouteGroupAdmin {
+			methods = adminCORSMethods
+		}
+		policy, err := newCORSPolicy(policyConfig, methods)
+		if err != nil {
+			return nil, fmt.Errorf("cors.groups.%s: %w", group, err)
+		}
+		cors.policies[group] = policy
+	}
+	return cors, nil
+}
+
+func newCORSPolicy(cfg models.CORSPolicy, defaultMethods []string) (*corsPolicy, error) {
+	policy := &corsPolicy{
+		disabled:    cfg.Disabled,
+		methods:     make(map[string]bool),
+		credentials: cfg.AllowCredentials,
+	}
+
+	origins := cfg.AllowedOrigins
+	if len(origins) == 0 {
+		origins = defaultCORSOrigins
+	}
+	for _, origin := range origins {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		if origin == "*" {
+			policy.anyOrigin = true
+			continue
+		}
+		if _, err := path.Match(origin, ""); err != nil {
+			return nil, fmt.Errorf("来源通配格式错误: %q", origin)
+		}
+		policy.origins = append(policy.origins, origin)
+	}
+	if policy.anyOrigin && policy.credentials {
+		return nil, fmt.Errorf("allow_credentials 不能与 * 来源同时使用")
+	}
+
+	methods := cfg.AllowedMethods
+	if len(methods) == 0 {
+		methods = defaultMethods
+	}
+	allowMethods := make([]string, 0, len(methods))
+	for _, method := range methods {
+		method = strings.ToUpper(strings.TrimSpace(method))
+		policy.methods[method] = true
+		allowMethods = append(allowMethods, method)
+	}
+	policy.allowMethods = strings.Join(allowMethods, ", ")
+
+	headers := cfg.AllowedHeaders
+	if len(headers) == 0 {
+		headers = defaultCORSHeaders
+	}
+	for _, header := range headers {
+		if header == "*" {
+			policy.anyHeader = true
+		}
+	}
+	policy.allowHeaders = strings.Join(headers, ", ")
+	policy.exposeHeaders = strings.Join(cfg.ExposedHeaders, ", ")
+
+	maxAge := cfg.MaxAge
+	if maxAge <= 0 {
+		maxAge = defaultCORSMaxAge
+	}
+	policy.maxAge = strconv.Itoa(maxAge)
+	return policy, nil
+}
+
+// allowOrigin 判断来源是否允许，通配模式中的 * 不匹配 /
+func (p *corsPolicy) allowOrigin(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	for _, pattern := range p.origins {
+		if ok, _ := path.Match(pattern, origin); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Preflight 处理预检请求的全局中间件
+// 预检请求按 Access-Control-Request-Method 重新路由到目标接口，由目标路由组的 Policy 返回对应的策略，
+// 目标接口不存在时返回 404。重新路由时全局中间件会再执行一次，访问日志、指标和链路追踪按预检标记跳过，
+// 只由外层请求以 OPTIONS 方法记录一次
+func (cors *CORS) Preflight(engine *gin.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		r := c.Request
+		method := r.Header.Get("Access-Control-Request-Method")
+		if r.Method != http.MethodOptions || method == "" || r.Header.Get("Origin") == "" || isPreflight(r) {
+			c.Next()
+			return
+		}
+
+		c.Request = r.WithContext(context.WithValue(r.Context(), preflightKey{}, true))
+		c.Request.Method = method
+		engine.HandleContext(c)
+		c.Request.Method = http.MethodOptions
+		c.Abort()
+	}
+}
+
+// Policy 返回路由组的跨域中间件，需要放在认证中间件之前，预检请求不携带凭证
+func (cors *CORS) Policy(group string) gin.HandlerFunc {
+	policy, ok := cors.policies[group]
+	if !ok {
+		panic("未知的 CORS 路由组: " + group)
+	}
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		preflight := isPreflight(c.Request)
+		if origin == "" || policy.disabled {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		header.Add("Vary", "Origin")
+		if !policy.allowOrigin(origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if policy.anyOrigin {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if policy.credentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if policy.exposeHeaders != "" {
+				header.Set("Access-Control-Expose-Headers", policy.exposeHeaders)
+			}
+			c.Next()
+			return
+		}
+
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+		if !policy.methods[c.Request.Method] {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		header.Set("Access-Control-Allow-Methods", policy.allowMethods)
+		if policy.anyHeader {
+			// * 在携带凭证的请求中不生效，回显请求的头
+			if requested := c.GetHeader("Access-Control-Request-Headers"); requested != "" {
+				header.Set("Access-Control-Allow-Headers", requested)
+			}
+		} else {
+			header.Set("Access-Control-Allow-Headers", policy.allowHeaders)
+		}
+		header.Set("Access-Control-Max-Age", policy.maxAge)
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+func isPreflight(r *http.Request) bool {
+	preflight, _ := r.Context().Value(preflightKey{}).(bool)
+	return preflight
+}
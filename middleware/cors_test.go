@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"meili_dog/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// corsRouter 按 main.go 的方式注册三个路由组
+func corsRouter(t *testing.T, cfg models.CORSConfig) *gin.Engine {
+	t.Helper()
+	cors, err := NewCORS(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+
+	router := gin.New()
+	router.Use(cors.Preflight(router))
+	router.GET("/health", cors.Policy(models.RouteGroupPublic), ok)
+	router.POST("/search", cors.Policy(models.RouteGroupSearch), ok)
+	router.DELETE("/documents", cors.Policy(models.RouteGroupAdmin), ok)
+	return router
+}
+
+func TestCORSPolicy(t *testing.T) {
+	adminConfig := models.CORSConfig{Groups: map[string]models.CORSPolicy{
+		models.RouteGroupAdmin: {AllowedOrigins: []string{"https://*.example.com"}, AllowCredentials: true},
+		models.RouteGroupSearch: {
+			AllowedOrigins: []string{"https://shop.example.com"},
+			AllowedMethods: []string{"get", "post"},
+			AllowedHeaders: []string{"*"},
+			ExposedHeaders: []string{RequestIDHeader},
+		},
+	}}
+
+	tests := []struct {
+		name       string
+		cfg        models.CORSConfig
+		method     string
+		target     string
+		origin     string
+		preflight  string // Access-Control-Request-Method，为空表示普通请求
+		reqHeaders string
+		wantStatus int
+		wantHeader map[string]string // 期望的响应头，值为空表示不应出现
+	}{
+		{
+			name: "默认策略允许任意来源", method: http.MethodGet, target: "/health", origin: "https://a.com",
+			wantStatus: http.StatusOK,
+			wantHeader: map[string]string{"Access-Control-Allow-Origin": "*"},
+		},
+		{
+			name: "默认策略预检", method: http.MethodOptions, target: "/search", origin: "https://a.com", preflight: http.MethodPost,
+			wantStatus: http.StatusNoContent,
+			wantHeader: map[string]string{"Access-Control-Allow-Origin": "*", "Access-Control-Allow-Methods": "GET, POST", "Access-Control-Max-Age": "600"},
+		},
+		{
+			name: "未配置 admin 时禁止预检", method: http.MethodOptions, target: "/documents", origin: "https://a.com", preflight: http.MethodDelete,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "未配置 admin 时普通请求不带跨域头", method: http.MethodDelete, target: "/documents", origin: "https://a.com",
+			wantStatus: http.StatusOK,
+			wantHeader: map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name: "预检目标接口不存在", method: http.MethodOptions, target: "/unknown", origin: "https://a.com", preflight: http.MethodGet,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "admin 通配来源和凭证", cfg: adminConfig, method: http.MethodOptions, target: "/documents", origin: "https://app.example.com", preflight: http.MethodDelete,
+			wantStatus: http.StatusNoContent,
+			wantHeader: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Allow-Methods":     "GET, POST, PUT, PATCH, DELETE",
+			},
+		},
+		{
+			name: "admin 来源不匹配", cfg: adminConfig, method: http.MethodOptions, target: "/documents", origin: "https://example.org", preflight: http.MethodDelete,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "search 组替换默认策略", cfg: adminConfig, method: http.MethodOptions, target: "/search", origin: "https://a.com", preflight: http.MethodPost,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "public 组仍使用默认策略", cfg: adminConfig, method: http.MethodGet, target: "/health", origin: "https://a.com",
+			wantStatus: http.StatusOK,
+			wantHeader: map[string]string{"Access-Control-Allow-Origin": "*"},
+		},
+		{
+			name: "允许任意请求头时回显", cfg: adminConfig, method: http.MethodOptions, target: "/search", origin: "https://shop.example.com", preflight: http.MethodPost,
+			reqHeaders: "X-Custom, Content-Type",
+			wantStatus: http.StatusNoContent,
+			wantHeader: map[string]string{"Access-Control-Allow-Headers": "X-Custom, Content-Type", "Access-Control-Allow-Methods": "GET, POST"},
+		},
+		{
+			name: "普通请求返回可读取的响应头", cfg: adminConfig, method: http.MethodPost, target: "/search", origin: "https://shop.example.com",
+			wantStatus: http.StatusOK,
+			wantHeader: map[string]string{"Access-Control-Allow-Origin": "https://shop.example.com", "Access-Control-Expose-Headers": RequestIDHeader},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := corsRouter(t, tt.cfg)
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			req.Header.Set("Origin", tt.origin)
+			if tt.preflight != "" {
+				req.Header.Set("Access-Control-Request-Method", tt.preflight)
+			}
+			if tt.reqHeaders != "" {
+				req.Header.Set("Access-Control-Request-Headers", tt.reqHeaders)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("状态码 = %d，期望 %d", w.Code, tt.wantStatus)
+			}
+			for name, want := range tt.wantHeader {
+				if got := w.Header().Get(name); got != want {
+					t.Errorf("%s = %q，期望 %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewCORSErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  models.CORSConfig
+	}{
+		{name: "未知的路由组", cfg: models.CORSConfig{Groups: map[string]models.CORSPolicy{"internal": {}}}},
+		{name: "凭证与任意来源", cfg: models.CORSConfig{Default: models.CORSPolicy{AllowCredentials: true}}},
+		{name: "通配格式错误", cfg: models.CORSConfig{Groups: map[string]models.CORSPolicy{models.RouteGroupAdmin: {AllowedOrigins: []string{"https://[.example.com"}}}}},
+	}
+
+	for _, tt := range tests {
+		if _, err := NewCORS(tt.cfg); err == nil {
+			t.Errorf("%s: NewCORS() 成功，期望出错", tt.name)
+		}
+	}
+}
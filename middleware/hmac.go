@@ -0,0 +1,271 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"meili_dog/models"
+)
+
+// 签名请求的请求头
+const (
+	HMACKeyHeader         = "X-Auth-Key"
+	HMACTimestampHeader   = "X-Auth-Timestamp"
+	HMACNonceHeader       = "X-Auth-Nonce"
+	HMACContentHashHeader = "X-Content-SHA256"
+	HMACSignatureHeader   = "X-Auth-Signature"
+)
+
+const (
+	defaultHMACMaxSkew = 300 * time.Second
+	maxSignedBodySize  = 64 << 20 // 非导入接口的请求体读入内存校验摘要
+)
+
+// signedImportPathSuffix 批量导入接口的路径后缀，只有导入接口接受流式请求体
+const signedImportPathSuffix = "/documents/import"
+
+var (
+	errBodyTooLarge       = errors.New("签名请求的请求体过大")
+	errBodyDigestMismatch = errors.New("请求体与 " + HMACContentHashHeader + " 不一致")
+	// errStreamingMediaType 流式请求体只能发送到批量导入接口
+	errStreamingMediaType = errors.New("只有批量导入接口接受 multipart、NDJSON 和 CSV 请求体")
+	// errBodySpool 导入接口的请求体无法写入临时文件
+	errBodySpool = errors.New("保存签名请求的请求体失败")
+)
+
+// noncePattern 随机串只允许字母、数字、- 和 _，长度 16 到 128
+var noncePattern = regexp.MustCompile(`^[A-Za-z0-9_\-]{16,128}$`)
+
+// streamingMediaTypes 流式导入的请求体类型，其他接口不接受
+var streamingMediaTypes = map[string]bool{
+	"multipart/form-data":  true,
+	"application/x-ndjson": true,
+	"application/ndjson":   true,
+	"application/jsonl":    true,
+	"text/csv":             true,
+}
+
+// hmacAuthenticator HMAC-SHA256 签名请求认证
+// 签名内容为 METHOD\nREQUEST_URI\nTIMESTAMP\nNONCE\nX-Content-SHA256，签名以十六进制放在 X-Auth-Signature 中
+// 签名只覆盖请求头中的请求体摘要，请求体在交给处理器之前与摘要比对，随机串在时间偏差窗口内只能使用一次
+type hmacAuthenticator struct {
+	keys    map[string]models.HMACKeyConfig
+	maxSkew time.Duration
+	nonces  *nonceCache
+}
+
+func newHMACAuthenticator(cfg models.HMACConfig) (*hmacAuthenticator, error) {
+	if len(cfg.Keys) == 0 {
+		return nil, fmt.Errorf("未配置 auth.hmac.keys")
+	}
+
+	authenticator := &hmacAuthenticator{
+		keys:    make(map[string]models.HMACKeyConfig, len(cfg.Keys)),
+		maxSkew: defaultHMACMaxSkew,
+		nonces:  newNonceCache(),
+	}
+	if cfg.MaxSkew > 0 {
+		authenticator.maxSkew = time.Duration(cfg.MaxSkew) * time.Second
+	}
+
+	for _, key := range cfg.Keys {
+		if key.ID == "" || key.Secret == "" {
+			return nil, fmt.Errorf("HMAC 密钥的 id 和 secret 不能为空")
+		}
+		if _, ok := authenticator.keys[key.ID]; ok {
+			return nil, fmt.Errorf("HMAC 密钥 id 重复: %s", key.ID)
+		}
+		if err := validateScopes(key.Scopes); err != nil {
+			return nil, fmt.Errorf("HMAC 密钥 %s: %w", key.ID, err)
+		}
+		authenticator.keys[key.ID] = key
+	}
+	return authenticator, nil
+}
+
+func (a *hmacAuthenticator) Name() string {
+	return "hmac"
+}
+
+// Authenticate 校验密钥、时间戳、随机串、签名和请求体摘要
+func (a *hmacAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	signature := r.Header.Get(HMACSignatureHeader)
+	if signature == "" {
+		return nil, errNoCredentials
+	}
+
+	keyID := r.Header.Get(HMACKeyHeader)
+	key, ok := a.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: 未知的签名密钥: %q", errInvalidCredentials, keyID)
+	}
+
+	timestamp := r.Header.Get(HMACTimestampHeader)
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s 格式错误", errInvalidCredentials, HMACTimestampHeader)
+	}
+	signedAt := time.Unix(seconds, 0)
+	skew := time.Since(signedAt)
+	if skew < -a.maxSkew || skew > a.maxSkew {
+		return nil, fmt.Errorf("%w: 签名已过期或时间偏差过大", errInvalidCredentials)
+	}
+
+	nonce := r.Header.Get(HMACNonceHeader)
+	if !noncePattern.MatchString(nonce) {
+		return nil, fmt.Errorf("%w: %s 应为 16 到 128 个字母、数字、- 或 _", errInvalidCredentials, HMACNonceHeader)
+	}
+	contentHash := strings.ToLower(r.Header.Get(HMACContentHashHeader))
+	digest, err := hex.DecodeString(contentHash)
+	if err != nil || len(digest) != sha256.Size {
+		return nil, fmt.Errorf("%w: %s 应为请求体 SHA-256 的十六进制", errInvalidCredentials, HMACContentHashHeader)
+	}
+
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: 签名格式错误", errInvalidCredentials)
+	}
+	mac := hmac.New(sha256.New, []byte(key.Secret))
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n%s", r.Method, r.URL.RequestURI(), timestamp, nonce, contentHash)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return nil, fmt.Errorf("%w: 签名不匹配", errInvalidCredentials)
+	}
+
+	// 签名有效后才记录随机串，避免伪造的请求占用随机串
+	if !a.nonces.use(keyID+":"+nonce, signedAt.Add(a.maxSkew)) {
+		return nil, fmt.Errorf("%w: %s 已使用过", errInvalidCredentials, HMACNonceHeader)
+	}
+
+	if err := verifyBody(r, digest); err != nil {
+		return nil, err
+	}
+	return &Principal{Subject: key.ID, Scopes: key.Scopes, Tenant: key.Tenant}, nil
+}
+
+// verifyBody 在处理器读取之前校验请求体摘要
+// 批量导入接口的请求体写入临时文件校验，不读入内存；其他接口的请求体读入内存校验，且不接受流式请求体
+func verifyBody(r *http.Request, digest []byte) error {
+	if r.Body == nil || r.Body == http.NoBody {
+		if !hmac.Equal(digest, emptyBodyDigest[:]) {
+			return fmt.Errorf("%w: %v", errInvalidCredentials, errBodyDigestMismatch)
+		}
+		return nil
+	}
+
+	if strings.HasSuffix(r.URL.Path, signedImportPathSuffix) {
+		body, err := spoolBody(r.Body, digest)
+		r.Body.Close()
+		if err != nil {
+			return err
+		}
+		r.Body = body
+		return nil
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); streamingMediaTypes[mediaType] {
+		return errStreamingMediaType
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxSignedBodySize+1))
+	r.Body.Close()
+	if err != nil {
+		return fmt.Errorf("%w: 读取请求体失败: %v", errInvalidCredentials, err)
+	}
+	if len(body) > maxSignedBodySize {
+		return errBodyTooLarge
+	}
+	if sum := sha256.Sum256(body); !hmac.Equal(sum[:], digest) {
+		return fmt.Errorf("%w: %v", errInvalidCredentials, errBodyDigestMismatch)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return nil
+}
+
+// emptyBodyDigest 空请求体的 SHA-256
+var emptyBodyDigest = sha256.Sum256(nil)
+
+// spoolBody 把请求体写入临时文件并计算摘要，摘要一致时返回从头读取该文件的请求体
+func spoolBody(body io.Reader, digest []byte) (*spooledBody, error) {
+	file, err := os.CreateTemp("", "meili-dog-body-*")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errBodySpool, err)
+	}
+	spooled := &spooledBody{File: file}
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(file, hash), body); err != nil {
+		spooled.Close()
+		var pathErr *os.PathError
+		if errors.As(err, &pathErr) {
+			return nil, fmt.Errorf("%w: %v", errBodySpool, err)
+		}
+		return nil, fmt.Errorf("%w: 读取请求体失败: %v", errInvalidCredentials, err)
+	}
+	if !hmac.Equal(hash.Sum(nil), digest) {
+		spooled.Close()
+		return nil, fmt.Errorf("%w: %v", errInvalidCredentials, errBodyDigestMismatch)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		spooled.Close()
+		return nil, fmt.Errorf("%w: %v", errBodySpool, err)
+	}
+	return spooled, nil
+}
+
+// spooledBody 已校验摘要的临时文件请求体，关闭时删除临时文件
+type spooledBody struct {
+	*os.File
+}
+
+func (b *spooledBody) Close() error {
+	err := b.File.Close()
+	os.Remove(b.Name())
+	return err
+}
+
+// nonceCache 记录时间偏差窗口内已使用的随机串，只在当前进程内有效
+type nonceCache struct {
+	mu        sync.Mutex
+	seen      map[string]time.Time // 随机串到过期时间
+	lastPrune time.Time
+}
+
+func newNonceCache() *nonceCache {
+	return &nonceCache{seen: make(map[string]time.Time)}
+}
+
+// use 记录随机串，在过期之前已经使用过时返回 false
+func (c *nonceCache) use(nonce string, expires time.Time) bool {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// 每分钟清理一次过期的随机串
+	if now.Sub(c.lastPrune) > time.Minute {
+		for key, exp := range c.seen {
+			if now.After(exp) {
+				delete(c.seen, key)
+			}
+		}
+		c.lastPrune = now
+	}
+
+	if exp, ok := c.seen[nonce]; ok && !now.After(exp) {
+		return false
+	}
+	c.seen[nonce] = expires
+	return true
+}
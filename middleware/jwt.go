@@ -0,0 +1,272 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"meili_dog/models"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	defaultScopeClaim  = "scope"     // 未配置 scope_claim 时读取权限范围的声明
+	defaultTenantClaim = "tenant_id" // 未配置 tenant_claim 时读取租户的声明
+)
+
+// jwtSigningMethods 允许的签名算法，只接受非对称算法，公钥来自 JWKS
+var jwtSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// jwtAuthenticator 使用本地 JWKS 文件中的公钥校验 Bearer JWT
+type jwtAuthenticator struct {
+	keys        map[string]crypto.PublicKey
+	issuer      string
+	audience    string
+	scopeClaim  string
+	tenantClaim string
+	parser      *jwt.Parser
+}
+
+func newJWTAuthenticator(cfg models.JWTConfig) (*jwtAuthenticator, error) {
+	if cfg.JWKSFile == "" {
+		return nil, fmt.Errorf("未配置 auth.jwt.jwks_file")
+	}
+
+	keys, err := loadJWKS(cfg.JWKSFile)
+	if err != nil {
+		return nil, err
+	}
+
+	authenticator := &jwtAuthenticator{
+		keys:        keys,
+		issuer:      cfg.Issuer,
+		audience:    cfg.Audience,
+		scopeClaim:  cfg.ScopeClaim,
+		tenantClaim: cfg.TenantClaim,
+		parser:      jwt.NewParser(jwt.WithValidMethods(jwtSigningMethods)),
+	}
+	if authenticator.scopeClaim == "" {
+		authenticator.scopeClaim = defaultScopeClaim
+	}
+	if authenticator.tenantClaim == "" {
+		authenticator.tenantClaim = defaultTenantClaim
+	}
+	return authenticator, nil
+}
+
+func (a *jwtAuthenticator) Name() string {
+	return "jwt"
+}
+
+func (a *jwtAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return nil, errNoCredentials
+	}
+	raw := strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+
+	claims := jwt.MapClaims{}
+	if _, err := a.parser.ParseWithClaims(raw, claims, a.keyFunc); err != nil {
+		return nil, fmt.Errorf("%w: JWT 无效: %v", errInvalidCredentials, err)
+	}
+	// 解析时只校验已有的 exp，不带 exp 的令牌永久有效，因此必须带 exp
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, fmt.Errorf("%w: JWT 缺少 exp 或已过期", errInvalidCredentials)
+	}
+	if a.issuer != "" && !claims.VerifyIssuer(a.issuer, true) {
+		return nil, fmt.Errorf("%w: JWT 签发者不匹配", errInvalidCredentials)
+	}
+	if a.audience != "" && !claims.VerifyAudience(a.audience, true) {
+		return nil, fmt.Errorf("%w: JWT 受众不匹配", errInvalidCredentials)
+	}
+
+	subject, _ := claims["sub"].(string)
+	return &Principal{
+		Subject: subject,
+		Scopes:  claimScopes(claims[a.scopeClaim]),
+		Tenant:  claimTenant(claims[a.tenantClaim]),
+		Claims:  claims,
+	}, nil
+}
+
+// keyFunc 按 kid 查找公钥，JWKS 中只有一个密钥时允许省略 kid
+func (a *jwtAuthenticator) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" && len(a.keys) == 1 {
+		for _, key := range a.keys {
+			return checkKeyType(token, key)
+		}
+	}
+
+	key, ok := a.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("未知的 kid: %q", kid)
+	}
+	return checkKeyType(token, key)
+}
+
+// checkKeyType 确保签名算法与公钥类型一致
+func checkKeyType(token *jwt.Token, key crypto.PublicKey) (interface{}, error) {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		switch token.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+			return key, nil
+		}
+	case *ecdsa.PublicKey:
+		if _, ok := token.Method.(*jwt.SigningMethodECDSA); ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("签名算法 %s 与公钥类型不匹配", token.Method.Alg())
+}
+
+// claimScopes 解析权限范围，支持空格分隔的字符串或字符串数组
+func claimScopes(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		scopes := make([]string, 0, len(v))
+		for _, item := range v {
+			if scope, ok := item.(string); ok {
+				scopes = append(scopes, scope)
+			}
+		}
+		return scopes
+	default:
+		return nil
+	}
+}
+
+// claimTenant 解析租户，支持字符串或数字
+func claimTenant(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+// jsonWebKey JWKS 中的单个公钥
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// loadJWKS 读取 JWKS 文件，支持 RSA 和 EC 公钥
+func loadJWKS(path string) (map[string]crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取 JWKS 文件失败: %w", err)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("解析 JWKS 文件失败: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for i, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		var key crypto.PublicKey
+		switch jwk.Kty {
+		case "RSA":
+			key, err = rsaPublicKey(jwk)
+		case "EC":
+			key, err = ecPublicKey(jwk)
+		default:
+			err = fmt.Errorf("不支持的密钥类型: %q", jwk.Kty)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("JWKS 第 %d 个密钥无效: %w", i+1, err)
+		}
+		if _, ok := keys[jwk.Kid]; ok {
+			return nil, fmt.Errorf("JWKS 中 kid 重复: %q", jwk.Kid)
+		}
+		keys[jwk.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS 文件中没有可用的签名公钥")
+	}
+	return keys, nil
+}
+
+func rsaPublicKey(jwk jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := decodeBigInt(jwk.N)
+	if err != nil {
+		return nil, fmt.Errorf("n: %w", err)
+	}
+	e, err := decodeBigInt(jwk.E)
+	if err != nil {
+		return nil, fmt.Errorf("e: %w", err)
+	}
+	if !e.IsInt64() || e.Int64() < 3 {
+		return nil, fmt.Errorf("e 无效")
+	}
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func ecPublicKey(jwk jsonWebKey) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch jwk.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("不支持的曲线: %q", jwk.Crv)
+	}
+
+	x, err := decodeBigInt(jwk.X)
+	if err != nil {
+		return nil, fmt.Errorf("x: %w", err)
+	}
+	y, err := decodeBigInt(jwk.Y)
+	if err != nil {
+		return nil, fmt.Errorf("y: %w", err)
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil, fmt.Errorf("公钥不在曲线 %s 上", jwk.Crv)
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	if value == "" {
+		return nil, fmt.Errorf("不能为空")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccessLog 记录每个请求的访问日志，5xx 记为 error，4xx 记为 warn
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 重新路由的预检请求由外层请求统一记录
+		if isPreflight(c.Request) {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		method := c.Request.Method
+		path := c.Request.URL.Path
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", method),
+			slog.String("path", path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("client_ip", c.ClientIP()),
+			slog.Int("size", c.Writer.Size()),
+		}
+		if principal, ok := PrincipalFrom(c); ok {
+			attrs = append(attrs, slog.String("principal", principal.Provider+":"+principal.Subject))
+		}
+		slog.LogAttrs(c.Request.Context(), level, "请求", attrs...)
+	}
+}
+
+// Recovery 捕获处理器中的 panic，记录堆栈并返回 500
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				slog.ErrorContext(c.Request.Context(), "请求处理异常", "panic", r, "stack", string(debug.Stack()))
+				if !c.Writer.Written() {
+					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+					return
+				}
+				c.Abort()
+			}
+		}()
+		c.Next()
+	}
+}
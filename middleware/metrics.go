@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"meili_dog/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute 未匹配任何路由的请求使用的路由标签，避免任意路径产生大量标签
+const unmatchedRoute = "unmatched"
+
+// Metrics 记录请求数、耗时和正在处理的请求数，路由标签使用路由模板
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 重新路由的预检请求由外层请求统一记录
+		if isPreflight(c.Request) {
+			c.Next()
+			return
+		}
+
+		done := metrics.RequestStarted()
+		defer done()
+
+		start := time.Now()
+		method := c.Request.Method
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		metrics.ObserveRequest(route, method, strconv.Itoa(c.Writer.Status()), time.Since(start))
+	}
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"meili_dog/metrics"
+	"meili_dog/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// scrapeCounter 返回指标接口中计数器样本的值，样本不存在时返回 0
+func scrapeCounter(t *testing.T, sample string) float64 {
+	t.Helper()
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if value, ok := strings.CutPrefix(line, sample+" "); ok {
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return n
+		}
+	}
+	return 0
+}
+
+func TestMetricsPreflight(t *testing.T) {
+	var logs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	cors, err := NewCORS(models.CORSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handled := 0
+	router := gin.New()
+	router.Use(AccessLog(), Metrics(), cors.Preflight(router))
+	router.POST("/metrics-preflight", cors.Policy(models.RouteGroupSearch), func(c *gin.Context) {
+		handled++
+		c.Status(http.StatusOK)
+	})
+
+	samples := map[string]float64{
+		`meili_dog_http_requests_total{method="OPTIONS",route="/metrics-preflight",status="204"}`: 1,
+		`meili_dog_http_requests_total{method="POST",route="/metrics-preflight",status="204"}`:    0,
+		`meili_dog_http_requests_total{method="OPTIONS",route="unmatched",status="404"}`:          1,
+		`meili_dog_http_requests_total{method="POST",route="unmatched",status="404"}`:             0,
+	}
+	counts := make(map[string]float64, len(samples))
+	for sample := range samples {
+		counts[sample] = scrapeCounter(t, sample)
+	}
+
+	for _, target := range []string{"/metrics-preflight", "/metrics-preflight-missing"} {
+		req := httptest.NewRequest(http.MethodOptions, target, nil)
+		req.Header.Set("Origin", "https://a.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if handled != 0 {
+		t.Errorf("预检请求执行了目标接口 %d 次", handled)
+	}
+
+	for sample, want := range samples {
+		if got := scrapeCounter(t, sample) - counts[sample]; got != want {
+			t.Errorf("%s 增加 %v，期望 %v", sample, got, want)
+		}
+	}
+
+	if got := strings.Count(logs.String(), `"msg":"请求"`); got != 2 {
+		t.Errorf("访问日志 %d 条，期望 2 条：\n%s", got, logs.String())
+	}
+	if strings.Contains(logs.String(), `"method":"POST"`) {
+		t.Errorf("访问日志不应记录重新路由的目标方法：\n%s", logs.String())
+	}
+}
@@ -0,0 +1,312 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"meili_dog/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 限流响应头
+const (
+	RateLimitLimitHeader     = "X-RateLimit-Limit"
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+	RateLimitResetHeader     = "X-RateLimit-Reset"
+)
+
+const defaultRateLimitStore = "memory"
+
+// defaultAuthRateLimitRate 认证失败按 IP 限流的默认速率，令牌桶容量默认为速率的两倍
+const defaultAuthRateLimitRate = 20
+
+// RateLimitStore 令牌桶存储，多个实例共享计数时实现该接口并通过 RegisterRateLimitStore 注册
+type RateLimitStore interface {
+	// Take 从 key 对应的令牌桶取一个令牌，桶每秒补充 rate 个令牌，最多 burst 个
+	Take(ctx context.Context, key string, rate float64, burst int) (RateLimitResult, error)
+}
+
+// RateLimitResult 一次取令牌的结果
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int           // 剩余令牌数
+	RetryAfter time.Duration // 被拒绝时距离下一个令牌的时间
+	Reset      time.Duration // 令牌桶补满的时间
+}
+
+var (
+	rateLimitStoresMu sync.Mutex
+	rateLimitStores   = map[string]func() (RateLimitStore, error){
+		defaultRateLimitStore: func() (RateLimitStore, error) { return NewMemoryRateLimitStore(), nil },
+	}
+)
+
+// RegisterRateLimitStore 注册令牌桶存储，配置 rate_limit.store = name 时使用
+func RegisterRateLimitStore(name string, factory func() (RateLimitStore, error)) {
+	rateLimitStoresMu.Lock()
+	defer rateLimitStoresMu.Unlock()
+	rateLimitStores[name] = factory
+}
+
+// RateLimiter 按路由组应用的令牌桶限流
+type RateLimiter struct {
+	store    RateLimitStore
+	failOpen bool
+	auth     *rateLimitQuota // 认证失败按 IP 限流，nil 表示不限流
+	blocked  *authBlocks     // 认证失败次数超出限制的 IP
+	policies map[string]*rateLimitPolicy
+}
+
+type rateLimitPolicy struct {
+	key     string
+	quota   rateLimitQuota
+	clients map[string]rateLimitQuota
+}
+
+type rateLimitQuota struct {
+	rate  float64
+	burst int
+}
+
+// NewRateLimiter 根据配置创建限流中间件，未启用时所有路由组都不限流
+func NewRateLimiter(cfg models.RateLimitConfig) (*RateLimiter, error) {
+	limiter := &RateLimiter{policies: make(map[string]*rateLimitPolicy)}
+	if !cfg.Enabled {
+		return limiter, nil
+	}
+
+	name := cfg.Store
+	if name == "" {
+		name = defaultRateLimitStore
+	}
+	rateLimitStoresMu.Lock()
+	factory, ok := rateLimitStores[name]
+	rateLimitStoresMu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("未知的限流存储: %q", name)
+	}
+	store, err := factory()
+	if err != nil {
+		return nil, fmt.Errorf("初始化限流存储 %s 失败: %w", name, err)
+	}
+	limiter.store = store
+	limiter.failOpen = cfg.FailOpen
+
+	if !cfg.Auth.Disabled {
+		rate, burst := cfg.Auth.Rate, cfg.Auth.Burst
+		if rate == 0 {
+			rate = defaultAuthRateLimitRate
+		}
+		if burst == 0 && rate > 0 {
+			burst = int(math.Ceil(rate * 2))
+		}
+		quota, err := newRateLimitQuota(rate, burst)
+		if err != nil {
+			return nil, fmt.Errorf("rate_limit.auth: %w", err)
+		}
+		limiter.auth = &quota
+		limiter.blocked = newAuthBlocks()
+	}
+
+	for group, policyConfig := range cfg.Groups {
+		switch group {
+		case models.RouteGroupPublic, models.RouteGroupSearch, models.RouteGroupAdmin:
+		default:
+			return nil, fmt.Errorf("未知的限流路由组: %q，可选 public、search、admin", group)
+		}
+		policy, err := newRateLimitPolicy(policyConfig)
+		if err != nil {
+			return nil, fmt.Errorf("rate_limit.groups.%s: %w", group, err)
+		}
+		limiter.policies[group] = policy
+	}
+	return limiter, nil
+}
+
+func newRateLimitPolicy(cfg models.RateLimitPolicy) (*rateLimitPolicy, error) {
+	policy := &rateLimitPolicy{key: cfg.Key, clients: make(map[string]rateLimitQuota, len(cfg.Clients))}
+	switch policy.key {
+	case "":
+		policy.key = models.RateLimitKeyIP
+	case models.RateLimitKeyIP, models.RateLimitKeyAPIKey, models.RateLimitKeyTenant:
+	default:
+		return nil, fmt.Errorf("未知的限流键: %q，可选 ip、api_key、tenant", policy.key)
+	}
+
+	quota, err := newRateLimitQuota(cfg.Rate, cfg.Burst)
+	if err != nil {
+		return nil, err
+	}
+	policy.quota = quota
+
+	for client, clientConfig := range cfg.Clients {
+		quota, err := newRateLimitQuota(clientConfig.Rate, clientConfig.Burst)
+		if err != nil {
+			return nil, fmt.Errorf("clients.%s: %w", client, err)
+		}
+		policy.clients[client] = quota
+	}
+	return policy, nil
+}
+
+func newRateLimitQuota(rate float64, burst int) (rateLimitQuota, error) {
+	if rate <= 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+		return rateLimitQuota{}, fmt.Errorf("rate 必须大于 0")
+	}
+	if burst < 0 {
+		return rateLimitQuota{}, fmt.Errorf("burst 不能为负数")
+	}
+	if burst == 0 {
+		burst = int(math.Ceil(rate))
+	}
+	return rateLimitQuota{rate: rate, burst: burst}, nil
+}
+
+// Limit 返回路由组的限流中间件，需要放在认证中间件之后以便按 API 密钥或租户限流
+func (l *RateLimiter) Limit(group string) gin.HandlerFunc {
+	policy, ok := l.policies[group]
+	if !ok {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	return func(c *gin.Context) {
+		client, key := policy.clientKey(c)
+		quota, ok := policy.clients[client]
+		if !ok {
+			quota = policy.quota
+		}
+		l.take(c, group+":"+key, quota)
+	}
+}
+
+// LimitAuth 返回按客户端 IP 限制认证失败的中间件，需要放在认证中间件之前
+// 认证失败的请求在认证中间件中直接返回 401，不会经过路由组的限流；只有返回 401 的请求消耗令牌，
+// 令牌用完后该 IP 的请求在认证之前返回 429，直到补充出下一个令牌，认证成功的请求不受影响
+func (l *RateLimiter) LimitAuth() gin.HandlerFunc {
+	if l.auth == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	quota := *l.auth
+	return func(c *gin.Context) {
+		key := "auth:ip:" + c.ClientIP()
+		if retryAfter, ok := l.blocked.check(key); ok {
+			c.Header("Retry-After", strconv.Itoa(max(ceilSeconds(retryAfter), 1)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "认证失败次数过多，请稍后重试"})
+			return
+		}
+
+		c.Next()
+		if c.Writer.Status() != http.StatusUnauthorized {
+			return
+		}
+
+		// 响应已经返回，存储出错时只记录日志
+		result, err := l.store.Take(c.Request.Context(), key, quota.rate, quota.burst)
+		if err != nil {
+			slog.ErrorContext(c.Request.Context(), "限流存储错误", "key", key, "error", err)
+			return
+		}
+		if !result.Allowed {
+			l.blocked.add(key, max(result.RetryAfter, time.Second))
+		}
+	}
+}
+
+// take 从令牌桶取一个令牌，取到时继续处理请求，否则返回 429
+// 存储出错时按 fail_open 放行请求或返回 503
+func (l *RateLimiter) take(c *gin.Context, key string, quota rateLimitQuota) {
+	result, err := l.store.Take(c.Request.Context(), key, quota.rate, quota.burst)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "限流存储错误", "key", key, "fail_open", l.failOpen, "error", err)
+		if l.failOpen {
+			c.Next()
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "限流服务不可用，请稍后重试"})
+		return
+	}
+
+	header := c.Writer.Header()
+	header.Set(RateLimitLimitHeader, strconv.Itoa(quota.burst))
+	header.Set(RateLimitRemainingHeader, strconv.Itoa(result.Remaining))
+	header.Set(RateLimitResetHeader, strconv.Itoa(ceilSeconds(result.Reset)))
+	if !result.Allowed {
+		header.Set("Retry-After", strconv.Itoa(max(ceilSeconds(result.RetryAfter), 1)))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "请求过于频繁，请稍后重试"})
+		return
+	}
+	c.Next()
+}
+
+// clientKey 返回用于匹配 clients 配额的客户端名称和令牌桶的键
+// 认证主体的名称带上认证方式，例如 api_key:frontend，避免不同认证方式的同名主体共用配额；
+// 没有主体标识的调用方（例如不含 sub 的 JWT）按 IP 限流，避免共用同一个令牌桶
+func (p *rateLimitPolicy) clientKey(c *gin.Context) (string, string) {
+	principal, ok := PrincipalFrom(c)
+	if ok && p.key == models.RateLimitKeyTenant && principal.Tenant != "" {
+		return principal.Tenant, "tenant:" + principal.Tenant
+	}
+	if ok && p.key != models.RateLimitKeyIP && principal.Subject != "" {
+		key := principal.Provider + ":" + principal.Subject
+		return key, key
+	}
+	return "", "ip:" + c.ClientIP()
+}
+
+// ceilSeconds 向上取整到秒
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// authBlocks 认证失败次数超出限制的键及解除时间，只在当前进程内有效
+type authBlocks struct {
+	mu        sync.Mutex
+	until     map[string]time.Time
+	lastPrune time.Time
+}
+
+func newAuthBlocks() *authBlocks {
+	return &authBlocks{until: make(map[string]time.Time)}
+}
+
+// check 返回键是否仍被限制以及剩余时间
+func (b *authBlocks) check(key string) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	until, ok := b.until[key]
+	if !ok {
+		return 0, false
+	}
+	remaining := time.Until(until)
+	if remaining <= 0 {
+		delete(b.until, key)
+		return 0, false
+	}
+	return remaining, true
+}
+
+// add 限制键一段时间，每分钟清理一次已解除的键
+func (b *authBlocks) add(key string, d time.Duration) {
+	now := time.Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if now.Sub(b.lastPrune) > time.Minute {
+		for k, until := range b.until {
+			if now.After(until) {
+				delete(b.until, k)
+			}
+		}
+		b.lastPrune = now
+	}
+	b.until[key] = now.Add(d)
+}
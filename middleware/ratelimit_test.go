@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"meili_dog/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// withPrincipal 测试用的认证中间件，principal 为 nil 时不认证
+func withPrincipal(principal *Principal) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if principal != nil {
+			c.Set(principalKey, principal)
+		}
+		c.Next()
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	limiter, err := NewRateLimiter(models.RateLimitConfig{
+		Enabled: true,
+		Auth:    models.RateLimitAuth{Disabled: true},
+		Groups: map[string]models.RateLimitPolicy{
+			models.RouteGroupSearch: {
+				Key:     models.RateLimitKeyAPIKey,
+				Rate:    0.001,
+				Burst:   2,
+				Clients: map[string]models.RateLimitQuota{"api_key:frontend": {Rate: 0.001, Burst: 4}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		principal *Principal
+		ip        string
+		allowed   int // 令牌用完前允许的请求数
+	}{
+		{name: "按 IP", ip: "10.0.0.1:1234", allowed: 2},
+		{name: "clients 配额", principal: &Principal{Subject: "frontend", Provider: "api_key"}, ip: "10.0.0.2:1234", allowed: 4},
+		{name: "其他认证方式的同名主体使用默认配额", principal: &Principal{Subject: "frontend", Provider: "hmac"}, ip: "10.0.0.2:1234", allowed: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/search", withPrincipal(tt.principal), limiter.Limit(models.RouteGroupSearch), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			for i := 0; i <= tt.allowed; i++ {
+				req := httptest.NewRequest(http.MethodGet, "/search", nil)
+				req.RemoteAddr = tt.ip
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				if i < tt.allowed {
+					if w.Code != http.StatusOK {
+						t.Fatalf("第 %d 次请求: 状态码 = %d，期望 200", i+1, w.Code)
+					}
+					continue
+				}
+				if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+					t.Fatalf("第 %d 次请求: 状态码 = %d，Retry-After = %q，期望 429", i+1, w.Code, w.Header().Get("Retry-After"))
+				}
+				if w.Header().Get(RateLimitRemainingHeader) != "0" {
+					t.Errorf("%s = %q，期望 0", RateLimitRemainingHeader, w.Header().Get(RateLimitRemainingHeader))
+				}
+			}
+		})
+	}
+}
+
+func TestLimitAuth(t *testing.T) {
+	limiter, err := NewRateLimiter(models.RateLimitConfig{
+		Enabled: true,
+		Auth:    models.RateLimitAuth{Rate: 0.001, Burst: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth, err := NewAuth(models.AuthConfig{
+		Providers: []string{"api_key"},
+		APIKeys:   []models.APIKeyConfig{{Name: "frontend", Key: "search-key", Scopes: []string{models.ScopeSearch}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.GET("/search", limiter.LimitAuth(), auth.Authenticate(), auth.Require(models.ScopeSearch), func(c *gin.Context) { c.Status(http.StatusOK) })
+	send := func(ip, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/search", nil)
+		req.RemoteAddr = ip
+		req.Header.Set(APIKeyHeader, key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 认证成功的请求不消耗令牌
+	for i := 0; i < 10; i++ {
+		if w := send("10.0.0.1:1", "search-key"); w.Code != http.StatusOK {
+			t.Fatalf("第 %d 次认证成功的请求: 状态码 = %d，期望 200", i+1, w.Code)
+		}
+	}
+
+	// 认证失败消耗令牌，令牌用完后的失败仍返回 401，之后该 IP 的请求在认证前返回 429
+	for i, want := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		if w := send("10.0.0.2:1", "wrong"); w.Code != want {
+			t.Fatalf("第 %d 次认证失败的请求: 状态码 = %d，期望 %d", i+1, w.Code, want)
+		}
+	}
+	w := send("10.0.0.2:1", "search-key")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("被限制的 IP: 状态码 = %d，Retry-After = %q，期望 429", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// 其他 IP 不受影响
+	if w := send("10.0.0.3:1", "search-key"); w.Code != http.StatusOK {
+		t.Errorf("其他 IP: 状态码 = %d，期望 200", w.Code)
+	}
+}
+
+// failingStore 总是出错的令牌桶存储
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, float64, int) (RateLimitResult, error) {
+	return RateLimitResult{}, errors.New("store down")
+}
+
+func TestRateLimitStoreError(t *testing.T) {
+	RegisterRateLimitStore("failing", func() (RateLimitStore, error) { return failingStore{}, nil })
+
+	for _, failOpen := range []bool{false, true} {
+		limiter, err := NewRateLimiter(models.RateLimitConfig{
+			Enabled:  true,
+			Store:    "failing",
+			FailOpen: failOpen,
+			Groups:   map[string]models.RateLimitPolicy{models.RouteGroupPublic: {Rate: 1}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		router := gin.New()
+		router.GET("/health", limiter.Limit(models.RouteGroupPublic), func(c *gin.Context) { c.Status(http.StatusOK) })
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+		want := http.StatusServiceUnavailable
+		if failOpen {
+			want = http.StatusOK
+		}
+		if w.Code != want {
+			t.Errorf("fail_open = %v: 状态码 = %d，期望 %d", failOpen, w.Code, want)
+		}
+	}
+}
+
+func TestNewRateLimiterErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  models.RateLimitConfig
+	}{
+		{name: "未知的存储", cfg: models.RateLimitConfig{Enabled: true, Store: "redis"}},
+		{name: "未知的路由组", cfg: models.RateLimitConfig{Enabled: true, Groups: map[string]models.RateLimitPolicy{"internal": {Rate: 1}}}},
+		{name: "未知的限流键", cfg: models.RateLimitConfig{Enabled: true, Groups: map[string]models.RateLimitPolicy{models.RouteGroupSearch: {Key: "user", Rate: 1}}}},
+		{name: "速率为 0", cfg: models.RateLimitConfig{Enabled: true, Groups: map[string]models.RateLimitPolicy{models.RouteGroupSearch: {}}}},
+		{name: "客户端配额无效", cfg: models.RateLimitConfig{Enabled: true, Groups: map[string]models.RateLimitPolicy{
+			models.RouteGroupSearch: {Rate: 1, Clients: map[string]models.RateLimitQuota{"api_key:frontend": {Rate: 1, Burst: -1}}},
+		}}},
+		{name: "认证失败限流速率为负数", cfg: models.RateLimitConfig{Enabled: true, Auth: models.RateLimitAuth{Rate: -1}}},
+	}
+
+	for _, tt := range tests {
+		if _, err := NewRateLimiter(tt.cfg); err == nil {
+			t.Errorf("%s: NewRateLimiter() 成功，期望出错", tt.name)
+		}
+	}
+}
+
+func TestAuthBlocks(t *testing.T) {
+	blocks := newAuthBlocks()
+	blocks.add("a", time.Hour)
+	blocks.add("b", -time.Second)
+
+	if remaining, ok := blocks.check("a"); !ok || remaining <= 0 {
+		t.Errorf("check(a) = %v, %v，期望仍被限制", remaining, ok)
+	}
+	if _, ok := blocks.check("b"); ok {
+		t.Error("check(b) 期望已解除限制")
+	}
+	if _, ok := blocks.check("c"); ok {
+		t.Error("check(c) 期望没有限制")
+	}
+}
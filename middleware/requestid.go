@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"meili_dog/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader 请求 ID 请求头和响应头
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength 沿用调用方传入的请求 ID 的最大长度
+const maxRequestIDLength = 128
+
+// RequestID 为每个请求分配请求 ID，调用方传入合法的 X-Request-ID 时沿用
+// 请求 ID 写入响应头和请求上下文，JSON 格式的错误响应中会加上 request_id 字段
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 重新路由的预检请求已经分配过请求 ID
+		if logging.RequestID(c.Request.Context()) != "" {
+			c.Next()
+			return
+		}
+
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Header(RequestIDHeader, id)
+
+		c.Writer = &errorBodyWriter{ResponseWriter: c.Writer, requestID: id}
+		c.Next()
+	}
+}
+
+// validRequestID 只接受可打印的 ASCII 字符，避免日志注入
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// errorBodyWriter 为状态码不小于 400 的 JSON 响应体加上 request_id 字段
+// gin 渲染 JSON 时一次写入完整的响应体，因此只处理第一次写入
+type errorBodyWriter struct {
+	gin.ResponseWriter
+	requestID string
+}
+
+func (w *errorBodyWriter) Write(data []byte) (int, error) {
+	if !w.isJSONError() {
+		return w.ResponseWriter.Write(data)
+	}
+	if _, err := w.ResponseWriter.Write(withRequestID(data, w.requestID)); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+func (w *errorBodyWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// isJSONError 是否为尚未开始发送的 JSON 错误响应
+func (w *errorBodyWriter) isJSONError() bool {
+	return !w.Written() && w.Status() >= http.StatusBadRequest &&
+		strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")
+}
+
+// withRequestID 在 JSON 对象开头插入 request_id，保留其余字段的顺序；不是完整的 JSON 对象或已有该字段时原样返回
+func withRequestID(body []byte, id string) []byte {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return body
+	}
+	if _, ok := fields["request_id"]; ok {
+		return body
+	}
+
+	trimmed := bytes.TrimLeft(body, " \t\r\n")
+	value, _ := json.Marshal(id)
+	var out bytes.Buffer
+	out.WriteString(`{"request_id":`)
+	out.Write(value)
+	if len(fields) > 0 {
+		out.WriteByte(',')
+	}
+	out.Write(trimmed[1:])
+	return out.Bytes()
+}
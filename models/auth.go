@@ -0,0 +1,81 @@
+package models
+
+import "time"
+
+// 认证权限范围
+const (
+	ScopeAdmin  = "admin"  // 设置、文档、任务等管理接口，包含所有权限
+	ScopeSearch = "search" // 搜索类接口
+)
+
+// 路由组，跨域和限流策略按路由组配置
+const (
+	RouteGroupPublic = "public" // 健康检查等无需认证的接口
+	RouteGroupSearch = "search" // 搜索类接口
+	RouteGroupAdmin  = "admin"  // 设置、文档、任务等管理接口
+)
+
+// AuthConfig 认证配置，providers 为空时不启用认证
+type AuthConfig struct {
+	Providers    []string       `toml:"providers"`     // 启用的认证方式，按顺序尝试：api_key、hmac、jwt
+	PublicSearch bool           `toml:"public_search"` // 搜索类接口无需认证
+	APIKeys      []APIKeyConfig `toml:"api_keys"`
+	HMAC         HMACConfig     `toml:"hmac"`
+	JWT          JWTConfig      `toml:"jwt"`
+}
+
+// APIKeyConfig 静态 API 密钥，通过 X-API-Key 请求头传入
+type APIKeyConfig struct {
+	Name   string   `toml:"name"`
+	Key    string   `toml:"key" secret:"true"`
+	Scopes []string `toml:"scopes"`
+	Tenant string   `toml:"tenant"` // 多租户模式下该密钥所属的租户
+}
+
+// HMACConfig 签名请求配置
+type HMACConfig struct {
+	MaxSkew int             `toml:"max_skew"` // 允许的时间偏差（秒），默认 300
+	Keys    []HMACKeyConfig `toml:"keys"`
+}
+
+// HMACKeyConfig 签名密钥
+type HMACKeyConfig struct {
+	ID     string   `toml:"id"`
+	Secret string   `toml:"secret" secret:"true"`
+	Scopes []string `toml:"scopes"`
+	Tenant string   `toml:"tenant"` // 多租户模式下该密钥所属的租户
+}
+
+// JWTConfig JWT 认证配置，公钥从本地 JWKS 文件读取
+type JWTConfig struct {
+	JWKSFile    string `toml:"jwks_file"`
+	Issuer      string `toml:"issuer"`       // 为空时不校验
+	Audience    string `toml:"audience"`     // 为空时不校验
+	ScopeClaim  string `toml:"scope_claim"`  // 权限范围所在的声明，默认 scope
+	TenantClaim string `toml:"tenant_claim"` // 租户所在的声明，默认 tenant_id
+}
+
+// TenantConfig 多租户配置，启用后每次搜索都会附加租户过滤条件
+type TenantConfig struct {
+	Enabled bool   `toml:"enabled"`
+	Field   string `toml:"field"` // 文档中的租户字段，默认 tenant_id，必须是可过滤字段
+	Token   struct {
+		APIKeyUID string `toml:"api_key_uid"`           // 用于签发租户令牌的 Meilisearch 搜索密钥 UID
+		APIKey    string `toml:"api_key" secret:"true"` // 对应的密钥，不要使用主密钥
+		TTL       int    `toml:"ttl"`                   // 令牌有效期（秒），默认 3600
+	} `toml:"token"`
+}
+
+// TenantTokenRequest 签发租户令牌请求
+type TenantTokenRequest struct {
+	Tenant    string `json:"tenant"`     // 只有不属于任何租户的 admin 可以指定
+	ExpiresIn int    `json:"expires_in"` // 有效期（秒），不能超过配置的 ttl
+}
+
+// TenantTokenResponse 租户令牌响应，可直接用于请求 Meilisearch 搜索接口
+type TenantTokenResponse struct {
+	Token     string    `json:"token"`
+	Tenant    string    `json:"tenant"`
+	IndexUIDs []string  `json:"index_uids"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
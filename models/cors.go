@@ -0,0 +1,19 @@
+package models
+
+// CORSConfig 跨域配置，default 为默认策略，groups 按路由组整体替换默认策略
+// admin 组不使用默认策略，未配置时禁止跨域访问
+type CORSConfig struct {
+	Default CORSPolicy            `toml:"default"`
+	Groups  map[string]CORSPolicy `toml:"groups"` // 可选 public、search、admin
+}
+
+// CORSPolicy 跨域策略，未设置的字段使用内置默认值
+type CORSPolicy struct {
+	Disabled         bool     `toml:"disabled"`          // 不返回任何跨域响应头，浏览器的跨域请求会被拒绝
+	AllowedOrigins   []string `toml:"allowed_origins"`   // 支持 * 和 https://*.example.com 形式的通配，默认 ["*"]
+	AllowedMethods   []string `toml:"allowed_methods"`   // 默认 GET、POST，admin 组默认 GET、POST、PUT、PATCH、DELETE
+	AllowedHeaders   []string `toml:"allowed_headers"`   // 默认 Content-Type、Authorization 和认证请求头，* 表示允许请求的所有头
+	ExposedHeaders   []string `toml:"exposed_headers"`   // 允许浏览器读取的响应头
+	AllowCredentials bool     `toml:"allow_credentials"` // 不能与 * 来源同时使用
+	MaxAge           int      `toml:"max_age"`           // 预检结果缓存时间（秒），默认 600
+}
@@ -0,0 +1,35 @@
+package models
+
+// 过滤操作符
+const (
+	FilterOpEq      = "eq"       // field = value
+	FilterOpNeq     = "neq"      // field != value
+	FilterOpGt      = "gt"       // field > value
+	FilterOpGte     = "gte"      // field >= value
+	FilterOpLt      = "lt"       // field < value
+	FilterOpLte     = "lte"      // field <= value
+	FilterOpIn      = "in"       // field IN [v1, v2]
+	FilterOpExists  = "exists"   // field EXISTS
+	FilterOpIsNull  = "is_null"  // field IS NULL
+	FilterOpIsEmpty = "is_empty" // field IS EMPTY
+	FilterOpTo      = "to"       // field value TO to
+)
+
+// FilterNode 过滤表达式节点
+// 逻辑节点只设置 and/or/not 之一，条件节点设置 field/op/value
+//
+// 示例：
+//
+//	{"and": [
+//	  {"field": "genre", "op": "eq", "value": "Action"},
+//	  {"not": {"field": "price", "op": "to", "value": 10, "to": 20}}
+//	]}
+type FilterNode struct {
+	And   []FilterNode `json:"and,omitempty"`
+	Or    []FilterNode `json:"or,omitempty"`
+	Not   *FilterNode  `json:"not,omitempty"`
+	Field string       `json:"field,omitempty"`
+	Op    string       `json:"op,omitempty"`
+	Value interface{}  `json:"value,omitempty"`
+	To    interface{}  `json:"to,omitempty"` // 仅 to 操作符使用
+}
@@ -0,0 +1,39 @@
+package models
+
+// 限流键
+const (
+	RateLimitKeyIP     = "ip"      // 客户端 IP
+	RateLimitKeyAPIKey = "api_key" // 认证主体（API 密钥名称、HMAC 密钥 ID 或 JWT 的 sub），未认证或 JWT 没有 sub 时按 IP
+	RateLimitKeyTenant = "tenant"  // 租户，没有租户时按认证主体，未认证时按 IP
+)
+
+// RateLimitConfig 令牌桶限流配置，groups 中没有配置的路由组不限流
+type RateLimitConfig struct {
+	Enabled  bool                       `toml:"enabled"`
+	Store    string                     `toml:"store"`     // 令牌桶存储，默认 memory，多实例共享计数时注册自定义存储
+	FailOpen bool                       `toml:"fail_open"` // 存储出错时放行请求，默认返回 503
+	Auth     RateLimitAuth              `toml:"auth"`      // 认证前按客户端 IP 限流
+	Groups   map[string]RateLimitPolicy `toml:"groups"`    // 可选 public、search、admin
+}
+
+// RateLimitAuth 按客户端 IP 限制认证失败的次数，只有返回 401 的请求计数，用于限制暴力尝试凭证
+// 启用限流和认证时默认开启
+type RateLimitAuth struct {
+	Disabled bool    `toml:"disabled"`
+	Rate     float64 `toml:"rate"`  // 每秒允许的认证失败次数，默认 20
+	Burst    int     `toml:"burst"` // 令牌桶容量，默认为 rate 的两倍
+}
+
+// RateLimitPolicy 路由组的限流策略
+type RateLimitPolicy struct {
+	Key     string                    `toml:"key"`     // 限流键：ip、api_key、tenant，默认 ip
+	Rate    float64                   `toml:"rate"`    // 每秒补充的令牌数
+	Burst   int                       `toml:"burst"`   // 令牌桶容量，默认为 rate 向上取整
+	Clients map[string]RateLimitQuota `toml:"clients"` // 按客户端覆盖配额，键为认证方式:主体（如 api_key:frontend、hmac:svc、jwt:user-1）或租户
+}
+
+// RateLimitQuota 单个客户端的配额
+type RateLimitQuota struct {
+	Rate  float64 `toml:"rate"`
+	Burst int     `toml:"burst"`
+}
@@ -18,30 +18,100 @@ type SettingResponse struct {
 	Success  bool        `json:"success"`
 	Message  string      `json:"message,omitempty"`
 	TaskUID  int64       `json:"task_uid,omitempty"`
+	Task     *TaskState  `json:"task,omitempty"` // wait=true 时返回任务最终状态
 	Settings interface{} `json:"settings,omitempty"`
+	Snapshot int64       `json:"snapshot,omitempty"` // 修改前保存的设置快照版本
 	Error    string      `json:"error,omitempty"`
 }
 
+// SettingsPatchRequest 部分更新设置，只有请求中出现的字段会被更新
+type SettingsPatchRequest struct {
+	SearchableAttributes *[]string             `json:"searchable_attributes"`
+	FilterableAttributes *[]string             `json:"filterable_attributes"`
+	SortableAttributes   *[]string             `json:"sortable_attributes"`
+	DisplayedAttributes  *[]string             `json:"displayed_attributes"`
+	RankingRules         *[]string             `json:"ranking_rules"`
+	StopWords            *[]string             `json:"stop_words"`
+	Synonyms             *map[string][]string  `json:"synonyms"`
+	TypoTolerance        *TypoToleranceSetting `json:"typo_tolerance"`
+	DistinctAttribute    *string               `json:"distinct_attribute"` // 空字符串表示取消去重字段
+	Pagination           *PaginationSetting    `json:"pagination"`
+	Faceting             *FacetingSetting      `json:"faceting"`
+	ProximityPrecision   *string               `json:"proximity_precision"` // byWord 或 byAttribute
+	SeparatorTokens      *[]string             `json:"separator_tokens"`
+	NonSeparatorTokens   *[]string             `json:"non_separator_tokens"`
+	Dictionary           *[]string             `json:"dictionary"`
+}
+
+// TypoToleranceSetting 拼写容错设置
+type TypoToleranceSetting struct {
+	Enabled             *bool                `json:"enabled" toml:"enabled"`
+	MinWordSizeForTypos *MinWordSizeForTypos `json:"min_word_size_for_typos" toml:"min_word_size_for_typos"`
+	DisableOnWords      *[]string            `json:"disable_on_words" toml:"disable_on_words"`
+	DisableOnAttributes *[]string            `json:"disable_on_attributes" toml:"disable_on_attributes"`
+}
+
+// MinWordSizeForTypos 允许拼写错误的最小词长
+type MinWordSizeForTypos struct {
+	OneTypo  *int64 `json:"one_typo" toml:"one_typo"`
+	TwoTypos *int64 `json:"two_typos" toml:"two_typos"`
+}
+
+// PaginationSetting 分页设置
+type PaginationSetting struct {
+	MaxTotalHits *int64 `json:"max_total_hits" toml:"max_total_hits"`
+}
+
+// FacetingSetting 分面设置
+type FacetingSetting struct {
+	MaxValuesPerFacet *int64            `json:"max_values_per_facet" toml:"max_values_per_facet"`
+	SortFacetValuesBy map[string]string `json:"sort_facet_values_by" toml:"sort_facet_values_by"` // alpha 或 count
+}
+
 // CurrentSettingsResponse 当前设置响应
+// 同时作为设置快照的内容，toml 标签用于导出快照
 type CurrentSettingsResponse struct {
-	SearchableAttributes []string            `json:"searchable_attributes"`
-	FilterableAttributes []string            `json:"filterable_attributes"`
-	SortableAttributes   []string            `json:"sortable_attributes"`
-	RankingRules         []string            `json:"ranking_rules"`
-	DisplayedAttributes  []string            `json:"displayed_attributes"`
-	StopWords            []string            `json:"stop_words"`
-	Synonyms             map[string][]string `json:"synonyms"`
-	TypoTolerance        interface{}         `json:"typo_tolerance"`
+	SearchableAttributes []string              `json:"searchable_attributes" toml:"searchable_attributes"`
+	FilterableAttributes []string              `json:"filterable_attributes" toml:"filterable_attributes"`
+	SortableAttributes   []string              `json:"sortable_attributes" toml:"sortable_attributes"`
+	RankingRules         []string              `json:"ranking_rules" toml:"ranking_rules"`
+	DisplayedAttributes  []string              `json:"displayed_attributes" toml:"displayed_attributes"`
+	StopWords            []string              `json:"stop_words" toml:"stop_words"`
+	Synonyms             map[string][]string   `json:"synonyms" toml:"synonyms"`
+	TypoTolerance        *TypoToleranceSetting `json:"typo_tolerance" toml:"typo_tolerance"`
+	DistinctAttribute    *string               `json:"distinct_attribute" toml:"distinct_attribute"`
+	Pagination           *PaginationSetting    `json:"pagination" toml:"pagination"`
+	Faceting             *FacetingSetting      `json:"faceting" toml:"faceting"`
+	ProximityPrecision   string                `json:"proximity_precision,omitempty" toml:"proximity_precision,omitempty"`
+	// 以下设置需要较新版本的 Meilisearch，旧版本中为 null
+	SeparatorTokens    []string `json:"separator_tokens" toml:"separator_tokens"`
+	NonSeparatorTokens []string `json:"non_separator_tokens" toml:"non_separator_tokens"`
+	Dictionary         []string `json:"dictionary" toml:"dictionary"`
 }
 
 // SearchRequest 搜索请求参数
+// GET 请求从查询参数绑定，POST 请求从 JSON 请求体绑定
 type SearchRequest struct {
-	Query   string                 `form:"query" binding:"required"`
-	Page    int                    `form:"page,default=1"`
-	Limit   int                    `form:"limit,default=20"`
-	Offset  int                    `form:"-"` // 不绑定查询参数，由程序计算
-	Filters map[string]interface{} `form:"filters"`
-	Sort    []string               `form:"sort"`
+	Query  string `form:"query" json:"query" binding:"required_without=Cursor"`
+	Page   int    `form:"page" json:"page"`   // 页码，从 1 开始，不能与 offset 同时使用
+	Limit  int    `form:"limit" json:"limit"` // 每页数量，默认 search.default.limit
+	Offset int    `form:"-" json:"-"`         // 不绑定查询参数，由 page 或 offset 计算
+	// 跳过的结果数，page 和 offset 都未设置时使用 search.default.offset
+	RequestedOffset *int                   `form:"offset" json:"offset"`
+	Filters         map[string]interface{} `form:"filters" json:"filters"`
+	Filter          string                 `form:"filter" json:"-"` // GET 请求中 JSON 格式的过滤表达式
+	FilterExpr      *FilterNode            `form:"-" json:"filter"` // 过滤表达式，GET 请求由 Filter 解析得到
+	Sort            []string               `form:"sort" json:"sort"`
+	Facets          []string               `form:"facets" json:"facets"` // 分面字段，必须是可过滤字段
+	Highlight       *HighlightOptions      `form:"-" json:"highlight"`   // 覆盖配置中的高亮参数
+	Cursor          string                 `form:"cursor" json:"cursor"` // 游标分页，* 表示第一页，之后传入上一页返回的 nextCursor
+}
+
+// HighlightOptions 高亮参数
+type HighlightOptions struct {
+	Attributes []string `json:"attributes"`
+	PreTag     string   `json:"pre_tag"`
+	PostTag    string   `json:"post_tag"`
 }
 
 // SearchResponse 搜索响应
@@ -55,6 +125,131 @@ type SearchResponse struct {
 	TotalPages         int                      `json:"totalPages,omitempty"`
 	ProcessingTimeMs   int                      `json:"processingTimeMs"`
 	IndexUID           string                   `json:"indexUID"` // 返回使用的索引UID
+	FacetDistribution  interface{}              `json:"facetDistribution,omitempty"`
+	FacetStats         interface{}              `json:"facetStats,omitempty"`
+	NextCursor         string                   `json:"nextCursor,omitempty"` // 游标分页的下一页，没有下一页时为空
+}
+
+// ExportRequest 导出请求参数，简单过滤条件使用 filters[field]=value
+type ExportRequest struct {
+	Format string `form:"format"` // ndjson（默认）或 csv
+	Fields string `form:"fields"` // 逗号分隔的导出字段，CSV 未设置时使用 [fields] 中声明了类型的字段，索引没有配置字段类型时必须设置
+	Filter string `form:"filter"` // JSON 格式的过滤表达式
+}
+
+// FacetSearchRequest 分面值搜索请求参数
+type FacetSearchRequest struct {
+	FacetQuery string `form:"q"`      // 在分面取值中搜索的关键词
+	Query      string `form:"query"`  // 当前的搜索词
+	Filter     string `form:"filter"` // JSON 格式的过滤表达式
+}
+
+// FacetHit 分面取值及文档数量
+type FacetHit struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// FacetSearchResponse 分面值搜索响应
+type FacetSearchResponse struct {
+	FacetHits        []FacetHit `json:"facetHits"`
+	FacetName        string     `json:"facetName"`
+	FacetQuery       string     `json:"facetQuery"`
+	Query            string     `json:"query"`
+	ProcessingTimeMs int        `json:"processingTimeMs"`
+	IndexUID         string     `json:"indexUID"`
+}
+
+// MultiSearchRequest 多索引搜索请求
+// 设置 federation 时将所有结果合并为一个列表
+type MultiSearchRequest struct {
+	Queries    []MultiSearchQuery `json:"queries" binding:"required,min=1,dive"`
+	Federation *FederationOptions `json:"federation"`
+}
+
+// MultiSearchQuery 多索引搜索中的单个查询
+type MultiSearchQuery struct {
+	IndexUID string  `json:"index_uid" binding:"required"`
+	Weight   float64 `json:"weight"` // 联合搜索中的权重，默认 1
+	SearchRequest
+}
+
+// FederationOptions 联合搜索分页参数
+type FederationOptions struct {
+	Page  int `json:"page"`
+	Limit int `json:"limit"`
+}
+
+// MultiSearchResponse 多索引搜索响应
+type MultiSearchResponse struct {
+	Results []SearchResponse `json:"results"`
+}
+
+// DocumentDeleteRequest 删除文档请求参数，ids 与 filter/filters 二选一
+type DocumentDeleteRequest struct {
+	IDs     []interface{}          `json:"ids"`
+	Filters map[string]interface{} `json:"filters"`
+	Filter  *FilterNode            `json:"filter"`
+}
+
+// DocumentResponse 文档写入响应
+type DocumentResponse struct {
+	Success bool       `json:"success"`
+	Message string     `json:"message,omitempty"`
+	TaskUID int64      `json:"task_uid,omitempty"`
+	Task    *TaskState `json:"task,omitempty"` // wait=true 时返回任务最终状态
+	Error   string     `json:"error,omitempty"`
+}
+
+// ImportBatch 单个导入批次的结果
+type ImportBatch struct {
+	Batch     int    `json:"batch"`
+	Documents int    `json:"documents"`
+	TaskUID   int64  `json:"task_uid,omitempty"`
+	Status    string `json:"status,omitempty"` // wait=true 时为任务最终状态
+	Error     string `json:"error,omitempty"`
+}
+
+// ImportResult 批量导入结果
+type ImportResult struct {
+	Success        bool          `json:"success"`
+	IndexUID       string        `json:"index_uid"`
+	Format         string        `json:"format"`
+	TotalDocuments int           `json:"total_documents"`
+	FailedBatches  int           `json:"failed_batches"`
+	Batches        []ImportBatch `json:"batches"`
+	Error          string        `json:"error,omitempty"`
+}
+
+// TaskState Meilisearch 任务状态
+type TaskState struct {
+	UID        int64       `json:"uid"`
+	IndexUID   string      `json:"index_uid"`
+	Status     string      `json:"status"`
+	Type       string      `json:"type"`
+	Duration   string      `json:"duration,omitempty"`
+	EnqueuedAt time.Time   `json:"enqueued_at"`
+	StartedAt  *time.Time  `json:"started_at,omitempty"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+	Details    interface{} `json:"details,omitempty"`
+	Error      *TaskError  `json:"error,omitempty"`
+}
+
+// TaskError 任务失败原因
+type TaskError struct {
+	Message string `json:"message"`
+	Code    string `json:"code"`
+	Type    string `json:"type"`
+	Link    string `json:"link,omitempty"`
+}
+
+// TaskListResponse 任务列表响应
+type TaskListResponse struct {
+	Results []*TaskState `json:"results"`
+	Limit   int64        `json:"limit"`
+	From    int64        `json:"from"`
+	Next    int64        `json:"next"`
+	Total   int64        `json:"total"`
 }
 
 // ServerConfig 服务器配置
@@ -71,10 +266,21 @@ type SearchConfig struct {
 	Fields       FieldConfig        `toml:"-"`
 }
 
-// SearchDefault 默认搜索参数
+// search.default 未设置时的默认值，加载配置时的校验和处理请求时使用同一组默认值
+const (
+	DefaultSearchLimit    = 20   // 默认每页数量，与 Meilisearch 的默认值相同
+	DefaultMaxLimit       = 1000 // 每页最多返回的结果数
+	DefaultMaxPageDepth   = 1000 // offset + limit 的上限，与 Meilisearch 默认的 maxTotalHits 相同
+	DefaultMaxQueryLength = 1000 // 搜索词的最大字符数
+)
+
+// SearchDefault 默认搜索参数和限制
 type SearchDefault struct {
-	Limit  int `toml:"limit"`
-	Offset int `toml:"offset"`
+	Limit          int `toml:"limit"`            // 默认每页数量，默认 20
+	Offset         int `toml:"offset"`           // 请求未设置 page 和 offset 时的偏移量
+	MaxLimit       int `toml:"max_limit"`        // 每页最多返回的结果数，默认 1000
+	MaxPageDepth   int `toml:"max_page_depth"`   // offset + limit 的上限，默认 1000
+	MaxQueryLength int `toml:"max_query_length"` // 搜索词的最大字符数，默认 1000
 }
 
 // SearchOptimization 搜索优化参数
@@ -88,32 +294,184 @@ type SearchOptimization struct {
 	AttributesToSearchOn  []string `toml:"attributes_to_search_on"`
 }
 
-// FieldConfig 字段类型配置
+// 字段类型
+const (
+	FieldTypeString  = "string"
+	FieldTypeNumber  = "number"
+	FieldTypeBoolean = "boolean"
+	FieldTypeArray   = "array"
+	FieldTypeObject  = "object"
+)
+
+// FieldConfig 字段类型配置（对应 [fields.string]、[fields.number] 等），每个字段只能属于一种类型
 type FieldConfig struct {
-	StringFields  []string `toml:"string"`
-	NumberFields  []string `toml:"number"`
-	BooleanFields []string `toml:"boolean"`
-	ArrayFields   []string `toml:"array"`
-	ObjectFields  []string `toml:"object"`
+	ApplySettings bool       `toml:"apply_settings"` // 声明式设置未设置时，用 filterable、sortable 作为可过滤和可排序字段同步
+	StringFields  FieldGroup `toml:"string"`
+	NumberFields  FieldGroup `toml:"number"`
+	BooleanFields FieldGroup `toml:"boolean"`
+	ArrayFields   FieldGroup `toml:"array"`
+	ObjectFields  FieldGroup `toml:"object"`
+}
+
+// FieldGroup 同一类型的字段
+type FieldGroup struct {
+	Names      []string `toml:"names"`      // 字段名，点号表示嵌套字段
+	Filterable []string `toml:"filterable"` // 可过滤字段，必须在 names 中
+	Sortable   []string `toml:"sortable"`   // 可排序字段，必须在 names 中
+}
+
+// IndexConfig 单个索引配置（对应 [[indexes]]）
+type IndexConfig struct {
+	UID          string              `toml:"uid"`
+	Optimization *SearchOptimization `toml:"optimization"` // 未配置时沿用 search.optimization
+	Settings     *SettingsConfig     `toml:"settings"`     // 声明式索引设置
+	Fields       *FieldConfig        `toml:"fields"`       // 字段类型，未配置时沿用 [fields]
+}
+
+// SettingsConfig 声明式索引设置，未出现的设置项不受管理
+type SettingsConfig struct {
+	ApplyOnStartup       bool                `toml:"apply_on_startup"` // 启动时自动同步
+	SearchableAttributes []string            `toml:"searchable_attributes"`
+	Weights              map[string]int      `toml:"weights"` // 可搜索字段权重，权重越高越靠前
+	FilterableAttributes []string            `toml:"filterable_attributes"`
+	SortableAttributes   []string            `toml:"sortable_attributes"`
+	RankingRules         []string            `toml:"ranking_rules"`
+	StopWords            []string            `toml:"stop_words"`
+	Synonyms             map[string][]string `toml:"synonyms"`
+}
+
+// SettingChange 单个设置项的差异
+type SettingChange struct {
+	Setting string      `json:"setting"`
+	Current interface{} `json:"current"`
+	Desired interface{} `json:"desired"`
+}
+
+// SettingsSyncResult 单个索引的设置同步结果
+type SettingsSyncResult struct {
+	IndexUID string          `json:"index_uid"`
+	Changes  []SettingChange `json:"changes"`
+	TaskUID  int64           `json:"task_uid,omitempty"`
+	Snapshot int64           `json:"snapshot,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// SettingsSnapshot 设置快照，每次修改设置前自动保存
+type SettingsSnapshot struct {
+	Version   int64                   `json:"version" toml:"version"`
+	IndexUID  string                  `json:"index_uid" toml:"index_uid"`
+	Reason    string                  `json:"reason" toml:"reason"` // 触发快照的操作
+	CreatedAt time.Time               `json:"created_at" toml:"created_at"`
+	Settings  CurrentSettingsResponse `json:"settings" toml:"settings"`
+}
+
+// SnapshotInfo 快照列表项，不包含设置内容
+type SnapshotInfo struct {
+	Version   int64     `json:"version"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SnapshotListResponse 快照列表响应
+type SnapshotListResponse struct {
+	IndexUID  string         `json:"index_uid"`
+	Snapshots []SnapshotInfo `json:"snapshots"`
+}
+
+// SnapshotChange 两个快照之间单个设置项的差异
+type SnapshotChange struct {
+	Setting string      `json:"setting"`
+	From    interface{} `json:"from"`
+	To      interface{} `json:"to"`
+}
+
+// SnapshotDiffResponse 快照差异响应，to 为 current 表示与当前设置比较
+type SnapshotDiffResponse struct {
+	IndexUID string           `json:"index_uid"`
+	From     string           `json:"from"`
+	To       string           `json:"to"`
+	Changes  []SnapshotChange `json:"changes"`
 }
 
 // AppConfig 应用配置
 type AppConfig struct {
 	Server struct {
-		Address   string `toml:"address"`
-		APIKey    string `toml:"api_key"`
-		LocalPort int64  `toml:"local_port"`
+		Address    string `toml:"address"`
+		APIKey     string `toml:"api_key" secret:"true"`
+		APIKeyFile string `toml:"api_key_file"` // 从文件读取 API 密钥，不能与 api_key 同时设置
+		LocalPort  int64  `toml:"local_port"`
+
+		TrustedProxies []string `toml:"trusted_proxies"` // 可信代理的 IP 或网段，为空时直接使用连接的对端地址
 	} `toml:"server"`
 	Search struct {
-		IndexUID     string `toml:"index_uid"` // 添加索引UID配置
-		Optimization struct {
-			AttributesToCrop      []string `toml:"attributes_to_crop"`
-			AttributesToHighlight []string `toml:"attributes_to_highlight"`
-			AttributesToRetrieve  []string `toml:"attributes_to_retrieve"`
-			AttributesToSearchOn  []string `toml:"attributes_to_search_on"`
-			CropLength            int      `toml:"crop_length"`
-			HighlightPreTag       string   `toml:"highlight_pre_tag"`
-			HighlightPostTag      string   `toml:"highlight_post_tag"`
-		} `toml:"optimization"`
+		IndexUID     string             `toml:"index_uid"` // 默认索引UID
+		Default      SearchDefault      `toml:"default"`   // 默认搜索参数和限制，所有索引共用
+		Optimization SearchOptimization `toml:"optimization"`
+		Settings     *SettingsConfig    `toml:"settings"` // 默认索引的声明式设置
 	} `toml:"search"`
+	Indexes []IndexConfig `toml:"indexes"` // 允许访问的索引列表
+	Fields  FieldConfig   `toml:"fields"`  // 默认索引的字段类型，[[indexes]] 未配置 fields 时沿用
+	Import  struct {
+		BatchSize int `toml:"batch_size"` // 每批写入的文档数
+	} `toml:"import"`
+	Cache struct {
+		Enabled    bool `toml:"enabled"`
+		MaxEntries int  `toml:"max_entries"` // 最多缓存的搜索结果数，默认 1000
+		TTL        int  `toml:"ttl"`         // 缓存时间（秒），默认 60
+	} `toml:"cache"`
+	Metrics struct {
+		Disabled bool   `toml:"disabled"`
+		Path     string `toml:"path"`   // 指标路径，默认 /metrics
+		Listen   string `toml:"listen"` // 单独监听的地址，例如 :9090；为空时与 API 使用同一端口
+	} `toml:"metrics"`
+	Snapshots struct {
+		Dir         string `toml:"dir"`           // 快照保存目录
+		MaxPerIndex int    `toml:"max_per_index"` // 每个索引保留的快照数量
+	} `toml:"snapshots"`
+	Reload struct {
+		Disabled bool `toml:"disabled"` // 禁用配置文件变化检测，仍可通过 SIGHUP 重新加载
+		Interval int  `toml:"interval"` // 检查配置文件变化的间隔（秒），默认 2
+	} `toml:"reload"`
+	Log       LogConfig       `toml:"log"`
+	Tracing   TracingConfig   `toml:"tracing"`
+	Auth      AuthConfig      `toml:"auth"`
+	Tenant    TenantConfig    `toml:"tenant"`
+	CORS      CORSConfig      `toml:"cors"`
+	RateLimit RateLimitConfig `toml:"rate_limit"`
+}
+
+// LogConfig 日志配置
+type LogConfig struct {
+	Level  string `toml:"level"`  // debug、info、warn、error，默认 info
+	Format string `toml:"format"` // text、json，默认 text
+}
+
+// TracingConfig OpenTelemetry 链路追踪配置
+type TracingConfig struct {
+	Enabled     bool              `toml:"enabled"`
+	Exporter    string            `toml:"exporter"`              // otlp（默认）或 stdout
+	Endpoint    string            `toml:"endpoint"`              // OTLP HTTP 地址，例如 localhost:4318；为空时读取 OTEL_EXPORTER_OTLP_ENDPOINT
+	Insecure    bool              `toml:"insecure"`              // 使用 HTTP 而不是 HTTPS 连接 collector
+	Headers     map[string]string `toml:"headers" secret:"true"` // 发送到 collector 的额外请求头
+	ServiceName string            `toml:"service_name"`          // 默认 meili_dog
+	SampleRatio float64           `toml:"sample_ratio"`          // 采样比例，默认 1；上游已决定采样时沿用上游的决定
+}
+
+// ConfigResponse 当前生效的配置，密钥已隐藏
+type ConfigResponse struct {
+	Config          map[string]interface{} `json:"config"`           // 与配置文件结构相同
+	LoadedAt        time.Time              `json:"loaded_at"`        // 最近一次加载配置的时间
+	RestartRequired []string               `json:"restart_required"` // 已修改但需要重启才能生效的配置项
+}
+
+// SearchCacheStats 搜索缓存统计
+type SearchCacheStats struct {
+	Enabled       bool  `json:"enabled"`
+	Entries       int   `json:"entries"`
+	MaxEntries    int   `json:"max_entries"`
+	TTLSeconds    int   `json:"ttl_seconds"`
+	Hits          int64 `json:"hits"`
+	Misses        int64 `json:"misses"`
+	Evictions     int64 `json:"evictions"`
+	Invalidations int64 `json:"invalidations"`
 }
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"meili_dog/config"
+	"meili_dog/handlers"
+	"meili_dog/logging"
+	"meili_dog/models"
+)
+
+// defaultReloadInterval 检查配置文件变化的默认间隔
+const defaultReloadInterval = 2 * time.Second
+
+// reloader 收到 SIGHUP 或配置文件变化时重新加载配置
+type reloader struct {
+	mu      sync.Mutex // 串行执行重新加载
+	path    string
+	handler *handlers.SearchHandler
+}
+
+// watchConfig 监听 SIGHUP 和配置文件变化，新配置校验失败时保留当前配置
+func watchConfig(path string, cfg *models.AppConfig, handler *handlers.SearchHandler) {
+	r := &reloader{path: path, handler: handler}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			r.reload("signal")
+		}
+	}()
+
+	if cfg.Reload.Disabled {
+		return
+	}
+	interval := defaultReloadInterval
+	if cfg.Reload.Interval > 0 {
+		interval = time.Duration(cfg.Reload.Interval) * time.Second
+	}
+	go r.poll(interval)
+}
+
+// poll 定期检查配置文件的修改时间和大小
+// 使用轮询而不是文件系统通知，配置文件被编辑器或 ConfigMap 整体替换时也能检测到
+func (r *reloader) poll(interval time.Duration) {
+	last, _ := os.Stat(r.path)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		info, err := os.Stat(r.path)
+		if err != nil {
+			continue
+		}
+		if last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+			continue
+		}
+		last = info
+		r.reload("file")
+	}
+}
+
+func (r *reloader) reload(trigger string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg, err := config.LoadConfig(r.path)
+	if err != nil {
+		slog.Error("重新加载配置失败，继续使用当前配置", "trigger", trigger, "error", err)
+		return
+	}
+	restartRequired, err := r.handler.Reload(*cfg)
+	if err != nil {
+		slog.Error("重新加载配置失败，继续使用当前配置", "trigger", trigger, "error", err)
+		return
+	}
+	// 日志配置已经校验过，这里不会失败
+	if err := logging.Setup(cfg.Log); err != nil {
+		slog.Error("重新加载日志配置失败", "error", err)
+	}
+
+	slog.Info("重新加载配置", "trigger", trigger, "path", r.path)
+	if len(restartRequired) > 0 {
+		slog.Warn("部分配置项需要重启才能生效", "keys", restartRequired)
+	}
+}
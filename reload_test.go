@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"meili_dog/config"
+	"meili_dog/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// writeConfig 写入只设置 Meilisearch 地址和默认索引的配置文件
+func writeConfig(t *testing.T, path, indexUID string) {
+	t.Helper()
+	data := "[server]\naddress = \"http://127.0.0.1:7700\"\nlocal_port = 8081\n\n[search]\nindex_uid = \"" + indexUID + "\"\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// currentIndex 通过配置接口读取当前生效的默认索引
+func currentIndex(t *testing.T, handler *handlers.SearchHandler) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/admin/config", nil)
+	handler.GetConfig(c)
+
+	var resp struct {
+		Config struct {
+			Search struct {
+				IndexUID string `json:"index_uid"`
+			} `json:"search"`
+		} `json:"config"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Config.Search.IndexUID
+}
+
+func TestReloader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	writeConfig(t, path, "users")
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := handlers.NewSearchHandler(*cfg)
+	r := &reloader{path: path, handler: handler}
+
+	writeConfig(t, path, "products")
+	r.reload("test")
+	if got := currentIndex(t, handler); got != "products" {
+		t.Fatalf("重新加载后 search.index_uid = %q，期望 products", got)
+	}
+
+	// 配置无效时保留当前配置
+	if err := os.WriteFile(path, []byte("[server]\naddress = \"127.0.0.1\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r.reload("test")
+	if got := currentIndex(t, handler); got != "products" {
+		t.Fatalf("配置无效时 search.index_uid = %q，期望保留 products", got)
+	}
+
+	// 轮询检测到配置文件变化后重新加载
+	go r.poll(10 * time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+	writeConfig(t, path, "orders")
+	deadline := time.Now().Add(2 * time.Second)
+	for currentIndex(t, handler) != "orders" {
+		if time.Now().After(deadline) {
+			t.Fatalf("配置文件变化后未重新加载，search.index_uid = %q", currentIndex(t, handler))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,250 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"meili_dog/models"
+)
+
+// FieldNamePattern 允许的字段名，点号用于嵌套字段
+// 字段名会直接拼接进过滤表达式，字段类型、过滤、分面、租户字段和游标排序字段共用
+var FieldNamePattern = regexp.MustCompile(`^[A-Za-z0-9_\-]+(\.[A-Za-z0-9_\-]+)*$`)
+
+// numberPattern 十进制数字，排除 ParseFloat 接受但 Meilisearch 过滤语法不支持的 NaN、Inf 和十六进制
+var numberPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// Schema 索引文档的字段类型，创建后不再修改
+type Schema struct {
+	types         map[string]string // 字段名到类型
+	filterable    []string
+	sortable      []string
+	applySettings bool
+}
+
+// Problem 字段类型配置中的单个问题
+type Problem struct {
+	Key     string // 相对于 fields 的配置项路径，例如 number.filterable
+	Message string
+}
+
+// Error 字段类型配置无效
+type Error struct {
+	Problems []Problem
+}
+
+func (e *Error) Error() string {
+	parts := make([]string, 0, len(e.Problems))
+	for _, p := range e.Problems {
+		parts = append(parts, p.Key+": "+p.Message)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// group 单个类型的字段组
+type group struct {
+	fieldType string
+	fields    *models.FieldGroup
+}
+
+func groups(cfg *models.FieldConfig) []group {
+	return []group{
+		{models.FieldTypeString, &cfg.StringFields},
+		{models.FieldTypeNumber, &cfg.NumberFields},
+		{models.FieldTypeBoolean, &cfg.BooleanFields},
+		{models.FieldTypeArray, &cfg.ArrayFields},
+		{models.FieldTypeObject, &cfg.ObjectFields},
+	}
+}
+
+// New 根据字段类型配置创建 Schema，没有声明任何字段时返回 nil，配置无效时返回 *Error
+func New(cfg *models.FieldConfig) (*Schema, error) {
+	s := &Schema{types: make(map[string]string), applySettings: cfg.ApplySettings}
+	e := &Error{}
+	for _, g := range groups(cfg) {
+		for _, name := range g.fields.Names {
+			switch existing, ok := s.types[name]; {
+			case !FieldNamePattern.MatchString(name):
+				e.add(g.fieldType+".names", fmt.Sprintf("字段名不合法: %q", name))
+			case ok:
+				e.add(g.fieldType+".names", fmt.Sprintf("字段 %q 已声明为 %s", name, existing))
+			default:
+				s.types[name] = g.fieldType
+			}
+		}
+	}
+	for _, g := range groups(cfg) {
+		s.filterable = append(s.filterable, e.declared(s, g, "filterable", g.fields.Filterable)...)
+		s.sortable = append(s.sortable, e.declared(s, g, "sortable", g.fields.Sortable)...)
+	}
+
+	if len(e.Problems) > 0 {
+		return nil, e
+	}
+	if len(s.types) == 0 {
+		return nil, nil
+	}
+	sort.Strings(s.filterable)
+	sort.Strings(s.sortable)
+	return s, nil
+}
+
+func (e *Error) add(key, message string) {
+	e.Problems = append(e.Problems, Problem{Key: key, Message: message})
+}
+
+// declared 返回字段组中已声明类型的字段，其余字段记录为问题
+func (e *Error) declared(s *Schema, g group, key string, names []string) []string {
+	var result []string
+	for _, name := range names {
+		if s.types[name] != g.fieldType {
+			e.add(g.fieldType+"."+key, fmt.Sprintf("字段 %q 不在 %s.names 中", name, g.fieldType))
+			continue
+		}
+		result = append(result, name)
+	}
+	return result
+}
+
+// FieldType 返回字段的类型，object 和 array 字段的子字段类型未知，返回空字符串
+// 未声明的字段返回 false
+func (s *Schema) FieldType(field string) (string, bool) {
+	if fieldType, ok := s.types[field]; ok {
+		return fieldType, true
+	}
+	for i := strings.LastIndex(field, "."); i > 0; i = strings.LastIndex(field[:i], ".") {
+		switch s.types[field[:i]] {
+		case models.FieldTypeObject, models.FieldTypeArray:
+			return "", true
+		}
+	}
+	return "", false
+}
+
+// Coerce 按字段类型转换过滤值，查询参数中的值都是字符串，例如 number 字段的 "2" 转换为数字 2
+// 类型未知的字段原样返回
+func (s *Schema) Coerce(field string, value interface{}) (interface{}, error) {
+	fieldType, _ := s.FieldType(field)
+	switch fieldType {
+	case models.FieldTypeNumber:
+		switch v := value.(type) {
+		case string:
+			raw := strings.TrimSpace(v)
+			if !numberPattern.MatchString(raw) {
+				return nil, fmt.Errorf("字段 %s 是数字类型，无效的值: %q", field, v)
+			}
+			return json.Number(raw), nil
+		case bool:
+			return nil, fmt.Errorf("字段 %s 是数字类型，无效的值: %v", field, v)
+		}
+	case models.FieldTypeBoolean:
+		switch v := value.(type) {
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("字段 %s 是布尔类型，无效的值: %q", field, v)
+			}
+			return b, nil
+		case bool:
+			return v, nil
+		default:
+			return nil, fmt.Errorf("字段 %s 是布尔类型，无效的值: %v", field, v)
+		}
+	case models.FieldTypeString:
+		switch v := value.(type) {
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		case json.Number:
+			return v.String(), nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		}
+	}
+	return value, nil
+}
+
+// Fields 所有声明了类型的字段，按字段名排序
+func (s *Schema) Fields() []string {
+	fields := make([]string, 0, len(s.types))
+	for name := range s.types {
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// ApplySettings 是否用 Filterable 和 Sortable 补充声明式设置
+func (s *Schema) ApplySettings() bool {
+	return s.applySettings
+}
+
+// Filterable 声明为可过滤的字段
+func (s *Schema) Filterable() []string {
+	return s.filterable
+}
+
+// Sortable 声明为可排序的字段
+func (s *Schema) Sortable() []string {
+	return s.sortable
+}
+
+// JSONSchema 生成文档的 JSON Schema，嵌套字段生成嵌套的 properties
+// 可过滤和可排序字段分别标记 x-filterable 和 x-sortable
+func (s *Schema) JSONSchema(title string) map[string]interface{} {
+	root := map[string]interface{}{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title":   title,
+		"type":    "object",
+	}
+
+	names := make([]string, 0, len(s.types))
+	for name := range s.types {
+		names = append(names, name)
+	}
+	// 父字段先于子字段处理
+	sort.Strings(names)
+
+	for _, name := range names {
+		property := schemaProperty(root, name)
+		property["type"] = s.types[name]
+	}
+	for _, name := range s.filterable {
+		schemaProperty(root, name)["x-filterable"] = true
+	}
+	for _, name := range s.sortable {
+		schemaProperty(root, name)["x-sortable"] = true
+	}
+	return root
+}
+
+// schemaProperty 返回字段对应的 property，缺少的父字段按 object 创建
+func schemaProperty(root map[string]interface{}, name string) map[string]interface{} {
+	node := root
+	for _, part := range strings.Split(name, ".") {
+		// array 字段的子字段描述数组元素
+		if node["type"] == models.FieldTypeArray {
+			items, ok := node["items"].(map[string]interface{})
+			if !ok {
+				items = map[string]interface{}{"type": models.FieldTypeObject}
+				node["items"] = items
+			}
+			node = items
+		}
+		properties, ok := node["properties"].(map[string]interface{})
+		if !ok {
+			properties = make(map[string]interface{})
+			node["properties"] = properties
+		}
+		child, ok := properties[part].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{"type": models.FieldTypeObject}
+			properties[part] = child
+		}
+		node = child
+	}
+	return node
+}